@@ -0,0 +1,93 @@
+package notificationhubs
+
+import (
+	"context"
+
+	"github.com/daresaydigital/azure-notificationhubs-go/tagexpr"
+)
+
+// AudienceEstimator counts the devices a tag expression would reach
+type AudienceEstimator struct {
+	expr     tagexpr.Expr
+	estimate AudienceEstimate
+}
+
+// NewAudienceEstimator initializes and returns AudienceEstimator pointer
+func NewAudienceEstimator(expr tagexpr.Expr) *AudienceEstimator {
+	return &AudienceEstimator{
+		expr: expr,
+		estimate: AudienceEstimate{
+			Registrations: map[TargetPlatform]int{},
+			Installations: map[InstallationPlatform]int{},
+		},
+	}
+}
+
+// AddRegistration counts the registration if it matches the expression
+func (a *AudienceEstimator) AddRegistration(r RegistrationResult) {
+	if r.RegistrationContent == nil || r.RegistrationContent.RegisteredDevice == nil {
+		return
+	}
+	a.estimate.Scanned++
+	if MatchRegisteredDevice(a.expr, *r.RegistrationContent.RegisteredDevice) {
+		a.estimate.Matched++
+		a.estimate.Registrations[r.RegistrationContent.Target]++
+	}
+}
+
+// AddInstallation counts the installation if it matches the expression
+func (a *AudienceEstimator) AddInstallation(i Installation) {
+	a.estimate.Scanned++
+	if MatchInstallation(a.expr, i) {
+		a.estimate.Matched++
+		a.estimate.Installations[i.Platform]++
+	}
+}
+
+// Estimate returns the counts so far
+func (a *AudienceEstimator) Estimate() *AudienceEstimate {
+	estimate := a.estimate
+	estimate.Registrations = make(map[TargetPlatform]int, len(a.estimate.Registrations))
+	for platform, count := range a.estimate.Registrations {
+		estimate.Registrations[platform] = count
+	}
+	estimate.Installations = make(map[InstallationPlatform]int, len(a.estimate.Installations))
+	for platform, count := range a.estimate.Installations {
+		estimate.Installations[platform] = count
+	}
+	return &estimate
+}
+
+// EstimateAudience counts the registrations in the hub matching the expression
+// Installations can not be listed through the API, use an AudienceEstimator to include them
+func (h *NotificationHub) EstimateAudience(ctx context.Context, expr tagexpr.Expr) (*AudienceEstimate, error) {
+	estimator := NewAudienceEstimator(expr)
+	err := h.ForEachRegistration(ctx, func(r RegistrationResult) error {
+		estimator.AddRegistration(r)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return estimator.Estimate(), nil
+}
+
+// MatchRegisteredDevice identifies whether the expression targets the registered device
+func MatchRegisteredDevice(expr tagexpr.Expr, d RegisteredDevice) bool {
+	return tagexpr.Match(expr, d.Tags)
+}
+
+// MatchInstallation identifies whether the expression targets the installation,
+// including the $InstallationId and $UserId tags added by the hub
+func MatchInstallation(expr tagexpr.Expr, i Installation) bool {
+	return tagexpr.Match(expr, InstallationTags(i))
+}
+
+// InstallationTags returns the tags of the installation including the ones added by the hub
+func InstallationTags(i Installation) []string {
+	tags := append([]string{InstallationIDTag(i.InstallationID)}, i.Tags...)
+	if i.UserID != "" {
+		tags = append(tags, UserIDTag(i.UserID))
+	}
+	return tags
+}
@@ -0,0 +1,86 @@
+package notificationhubs_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"testing"
+
+	. "github.com/daresaydigital/azure-notificationhubs-go"
+	"github.com/daresaydigital/azure-notificationhubs-go/tagexpr"
+)
+
+func Test_EstimateAudience(t *testing.T) {
+	var (
+		nhub, mockClient = initTestItems()
+		requests         = 0
+	)
+
+	mockClient.execFunc = func(req *http.Request) ([]byte, *http.Response, error) {
+		requests++
+		if gotMethod := req.Method; gotMethod != getMethod {
+			t.Errorf(errfmt, "method", getMethod, gotMethod)
+		}
+		header := http.Header{}
+		switch requests {
+		case 1:
+			if gotURL := req.URL.String(); gotURL != registrationsURL {
+				t.Errorf(errfmt, "URL", registrationsURL, gotURL)
+			}
+			header.Set("X-MS-ContinuationToken", "page2")
+		case 2:
+			if token := req.URL.Query().Get("ContinuationToken"); token != "page2" {
+				t.Errorf(errfmt, "ContinuationToken", "page2", token)
+			}
+		default:
+			t.Fatalf(errfmt, "requests", 2, requests)
+		}
+		data, e := ioutil.ReadFile("./fixtures/registrationsResult.xml")
+		if e != nil {
+			return nil, nil, e
+		}
+		return data, &http.Response{Header: header}, nil
+	}
+
+	estimate, err := nhub.EstimateAudience(context.Background(), tagexpr.MustParse("tag3 && !tag4"))
+	if err != nil {
+		t.Fatalf(errfmt, "error", nil, err)
+	}
+	expected := &AudienceEstimate{
+		Scanned:       8,
+		Matched:       4,
+		Registrations: map[TargetPlatform]int{ApplePlatform: 4},
+		Installations: map[InstallationPlatform]int{},
+	}
+	if !reflect.DeepEqual(estimate, expected) {
+		t.Errorf(errfmt, "estimate", expected, estimate)
+	}
+}
+
+func Test_AudienceEstimatorInstallations(t *testing.T) {
+	var (
+		estimator = NewAudienceEstimator(tagexpr.Or(tagexpr.Tag(UserIDTag("user1")), tagexpr.Tag("tag1")))
+		installs  = []Installation{
+			{InstallationID: "1", UserID: "user1", Platform: APNSPlatform},
+			{InstallationID: "2", UserID: "user2", Platform: GCMPlatform, Tags: []string{"tag1"}},
+			{InstallationID: "3", UserID: "user2", Platform: GCMPlatform, Tags: []string{"tag2"}},
+		}
+	)
+	for _, i := range installs {
+		estimator.AddInstallation(i)
+	}
+
+	expected := &AudienceEstimate{
+		Scanned:       3,
+		Matched:       2,
+		Registrations: map[TargetPlatform]int{},
+		Installations: map[InstallationPlatform]int{APNSPlatform: 1, GCMPlatform: 1},
+	}
+	if estimate := estimator.Estimate(); !reflect.DeepEqual(estimate, expected) {
+		t.Errorf(errfmt, "estimate", expected, estimate)
+	}
+	if !MatchInstallation(tagexpr.Tag(InstallationIDTag("3")), installs[2]) {
+		t.Errorf(errfmt, "installation ID tag match", true, false)
+	}
+}
@@ -0,0 +1,99 @@
+package notificationhubs
+
+import (
+	"context"
+	"errors"
+	"sort"
+)
+
+// ErrNotificationPending is returned when the outcome of a notification is requested before it was processed
+var ErrNotificationPending = errors.New("notification is still being processed")
+
+// prunableOutcomes are the outcomes meaning the handle will never be valid again
+var prunableOutcomes = map[NotificationOutcomeName]bool{
+	BadChannel:     true,
+	ExpiredChannel: true,
+	WrongToken:     true,
+}
+
+// DirectBatchOutcome reads the outcome of a direct batch send for every device handle in the batch
+// The telemetry is the one returned by SendDirectBatch and is only available for Standard tier Notification Hubs.
+// ErrNotificationPending is returned until the hub has finished processing the notification
+func (h *NotificationHub) DirectBatchOutcome(ctx context.Context, telemetry *NotificationTelemetry, deviceHandles []string) (*BatchOutcome, error) {
+	if telemetry == nil || telemetry.NotificationMessageID == "" {
+		return nil, errors.New("notificationhubs.DirectBatchOutcome: no notification message ID, telemetry requires a Standard tier hub")
+	}
+	details, _, err := h.NotificationDetails(ctx, telemetry.NotificationMessageID)
+	if err != nil {
+		return nil, err
+	}
+	if !details.IsTerminal() {
+		return nil, ErrNotificationPending
+	}
+
+	failures := map[string]NotificationOutcomeName{}
+	err = h.PnsErrorDetails(ctx, details, func(detail PnsErrorDetail) error {
+		failures[detail.Handle] = detail.Outcome
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	outcome := &BatchOutcome{
+		Details:  details,
+		Outcomes: make(map[string]NotificationOutcomeName, len(deviceHandles)),
+	}
+	for _, handle := range deviceHandles {
+		if failure, ok := failures[handle]; ok {
+			outcome.Outcomes[handle] = failure
+			continue
+		}
+		switch details.State {
+		case Completed:
+			outcome.Outcomes[handle] = Success
+		case NoTargetFound:
+			outcome.Outcomes[handle] = NoTargets
+		case Abandoned:
+			outcome.Outcomes[handle] = AbandonedNotificationMessages
+		case Canceled:
+			outcome.Outcomes[handle] = CanceledNotification
+		default:
+			outcome.Outcomes[handle] = UnknownError
+		}
+	}
+	return outcome, nil
+}
+
+// Succeeded returns the handles the notification was delivered to
+func (o *BatchOutcome) Succeeded() []string {
+	return o.handles(func(outcome NotificationOutcomeName) bool {
+		return outcome == Success
+	})
+}
+
+// Failed returns the handles the notification could not be delivered to
+func (o *BatchOutcome) Failed() []string {
+	return o.handles(func(outcome NotificationOutcomeName) bool {
+		return outcome != Success
+	})
+}
+
+// Prunable returns the handles reported as wrong, expired or bad, which should be removed
+func (o *BatchOutcome) Prunable() []string {
+	return o.handles(func(outcome NotificationOutcomeName) bool {
+		return prunableOutcomes[outcome]
+	})
+}
+
+// handles returns the handles whose outcome satisfies fn
+func (o *BatchOutcome) handles(fn func(NotificationOutcomeName) bool) []string {
+	var handles []string
+	for handle, outcome := range o.Outcomes {
+		if fn(outcome) {
+			handles = append(handles, handle)
+		}
+	}
+	sort.Strings(handles)
+	return handles
+}
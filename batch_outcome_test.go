@@ -0,0 +1,108 @@
+package notificationhubs_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	. "github.com/daresaydigital/azure-notificationhubs-go"
+)
+
+const (
+	notificationID        = "3288835312934927344-986564390439048203-1"
+	pnsErrorDetailsPrefix = "https://testhubblob.blob.core.windows.net/pnserrors/"
+)
+
+func Test_DirectBatchOutcome(t *testing.T) {
+	var (
+		nhub, mockClient = initTestItems()
+		handles          = []string{"ABCDEF", "QWERTY", "ZXCVBN", "ANDROIDID"}
+	)
+
+	mockClient.execFunc = func(req *http.Request) ([]byte, *http.Response, error) {
+		var (
+			gotURL  = req.URL.String()
+			fixture string
+		)
+		switch {
+		case strings.HasPrefix(gotURL, pnsErrorDetailsPrefix):
+			if req.Header.Get("Authorization") != "" {
+				t.Errorf(errfmt, "blob Authorization header", "", req.Header.Get("Authorization"))
+			}
+			fixture = "./fixtures/pnsErrorDetails.json"
+		default:
+			wantURL := "https://testhub-ns.servicebus.windows.net/testhub/messages/" + notificationID + "?api-version=" + telemetryAPIVersionValue
+			if gotURL != wantURL {
+				t.Errorf(errfmt, "URL", wantURL, gotURL)
+			}
+			fixture = "./fixtures/notificationDetails.xml"
+		}
+		data, e := ioutil.ReadFile(fixture)
+		if e != nil {
+			return nil, nil, e
+		}
+		return data, nil, nil
+	}
+
+	outcome, err := nhub.DirectBatchOutcome(context.Background(), &NotificationTelemetry{NotificationMessageID: notificationID}, handles)
+	if err != nil {
+		t.Fatalf(errfmt, "error", nil, err)
+	}
+
+	expected := map[string]NotificationOutcomeName{
+		"ABCDEF":    WrongToken,
+		"QWERTY":    Success,
+		"ZXCVBN":    Success,
+		"ANDROIDID": ExpiredChannel,
+	}
+	if !reflect.DeepEqual(outcome.Outcomes, expected) {
+		t.Errorf(errfmt, "outcomes", expected, outcome.Outcomes)
+	}
+	if got, want := outcome.Succeeded(), []string{"QWERTY", "ZXCVBN"}; !reflect.DeepEqual(got, want) {
+		t.Errorf(errfmt, "succeeded", want, got)
+	}
+	if got, want := outcome.Prunable(), []string{"ABCDEF", "ANDROIDID"}; !reflect.DeepEqual(got, want) {
+		t.Errorf(errfmt, "prunable", want, got)
+	}
+}
+
+func Test_DirectBatchOutcomePending(t *testing.T) {
+	nhub, mockClient := initTestItems()
+
+	mockClient.execFunc = func(req *http.Request) ([]byte, *http.Response, error) {
+		return []byte("<NotificationDetails><NotificationId>1</NotificationId><State>Processing</State></NotificationDetails>"), nil, nil
+	}
+
+	if _, err := nhub.DirectBatchOutcome(context.Background(), &NotificationTelemetry{NotificationMessageID: "1"}, []string{"a"}); err != ErrNotificationPending {
+		t.Errorf(errfmt, "error", ErrNotificationPending, err)
+	}
+	if _, err := nhub.DirectBatchOutcome(context.Background(), &NotificationTelemetry{}, []string{"a"}); err == nil {
+		t.Errorf(errfmt, "error", "no notification message ID", err)
+	}
+}
+
+func Test_DirectBatchOutcomeCanceled(t *testing.T) {
+	nhub, mockClient := initTestItems()
+
+	mockClient.execFunc = func(req *http.Request) ([]byte, *http.Response, error) {
+		return []byte("<NotificationDetails><NotificationId>1</NotificationId><State>Canceled</State></NotificationDetails>"), nil, nil
+	}
+
+	outcome, err := nhub.DirectBatchOutcome(context.Background(), &NotificationTelemetry{NotificationMessageID: "1"}, []string{"a", "b"})
+	if err != nil {
+		t.Fatalf(errfmt, "error", nil, err)
+	}
+	expected := map[string]NotificationOutcomeName{"a": CanceledNotification, "b": CanceledNotification}
+	if !reflect.DeepEqual(outcome.Outcomes, expected) {
+		t.Errorf(errfmt, "outcomes", expected, outcome.Outcomes)
+	}
+	if got, want := outcome.Failed(), []string{"a", "b"}; !reflect.DeepEqual(got, want) {
+		t.Errorf(errfmt, "failed", want, got)
+	}
+	if got := outcome.Prunable(); len(got) != 0 {
+		t.Errorf(errfmt, "prunable", nil, got)
+	}
+}
@@ -0,0 +1,157 @@
+package notificationhubs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+type (
+	// Limiter limits the rate of requests, Wait blocks until a request may be sent
+	Limiter interface {
+		Wait(ctx context.Context) error
+	}
+
+	// DirectBulkOptions configures how SendDirectBulk splits and sends the handles
+	DirectBulkOptions struct {
+		// BatchSize is the number of handles per request, defaults to and can not exceed 1,000
+		BatchSize int
+		// Concurrency is the maximum number of requests in flight, defaults to 4
+		Concurrency int
+		// Limiter is waited on before every request, or nil to send as fast as possible
+		Limiter Limiter
+		// Deduplicate removes duplicate handles before sending
+		Deduplicate bool
+		// Normalize trims whitespace from the handles, and brackets and spaces from Apple device tokens
+		Normalize bool
+	}
+
+	// DirectBulkResult is the aggregated result of a bulk direct send
+	DirectBulkResult struct {
+		Batches []DirectBatch
+	}
+
+	// DirectBatch is the result of sending to one batch of device handles
+	DirectBatch struct {
+		Handles   []string
+		Telemetry *NotificationTelemetry
+		Err       error
+	}
+)
+
+// SendDirectBulk publishes notification to any number of devices
+// The handles are split into batches of at most 1,000 which are sent concurrently.
+// The result lists every batch even if some of them failed, in which case an error is also returned
+func (h *NotificationHub) SendDirectBulk(ctx context.Context, n *Notification, deviceHandles []string, opts *DirectBulkOptions) (result *DirectBulkResult, err error) {
+	result, err = h.sendDirectBulk(ctx, n, deviceHandles, opts)
+	if err != nil {
+		return result, fmt.Errorf("notificationhubs.SendDirectBulk: %w", err)
+	}
+	return
+}
+
+// MessageIDs returns the notification message IDs of the successful batches
+func (r *DirectBulkResult) MessageIDs() []string {
+	var ids []string
+	for _, batch := range r.Batches {
+		if batch.Err == nil && batch.Telemetry != nil && batch.Telemetry.NotificationMessageID != "" {
+			ids = append(ids, batch.Telemetry.NotificationMessageID)
+		}
+	}
+	return ids
+}
+
+// Failed returns the batches that could not be sent
+func (r *DirectBulkResult) Failed() []DirectBatch {
+	var failed []DirectBatch
+	for _, batch := range r.Batches {
+		if batch.Err != nil {
+			failed = append(failed, batch)
+		}
+	}
+	return failed
+}
+
+// Err returns an error describing the failed batches, or nil if all batches were sent
+func (r *DirectBulkResult) Err() error {
+	failed := r.Failed()
+	if len(failed) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%d of %d batches failed, first error: %s", len(failed), len(r.Batches), failed[0].Err)
+}
+
+// sendDirectBulk splits the handles into batches and sends them concurrently
+func (h *NotificationHub) sendDirectBulk(ctx context.Context, n *Notification, deviceHandles []string, opts *DirectBulkOptions) (*DirectBulkResult, error) {
+	if opts == nil {
+		opts = &DirectBulkOptions{}
+	}
+	size := opts.BatchSize
+	if size < 1 || size > maxDirectBatchSize {
+		size = maxDirectBatchSize
+	}
+
+	handles := prepareHandles(n.Format, deviceHandles, opts.Normalize, opts.Deduplicate)
+	if len(handles) == 0 {
+		return nil, errors.New("no device handles to send to")
+	}
+
+	result := &DirectBulkResult{}
+	for start := 0; start < len(handles); start += size {
+		end := start + size
+		if end > len(handles) {
+			end = len(handles)
+		}
+		result.Batches = append(result.Batches, DirectBatch{Handles: handles[start:end]})
+	}
+
+	forEachParallel(len(result.Batches), opts.Concurrency, func(i int) {
+		batch := &result.Batches[i]
+		if batch.Err = ctx.Err(); batch.Err != nil {
+			return
+		}
+		if opts.Limiter != nil {
+			if batch.Err = opts.Limiter.Wait(ctx); batch.Err != nil {
+				return
+			}
+		}
+		_, batch.Telemetry, batch.Err = h.sendDirectBatch(ctx, n, batch.Handles)
+	})
+	return result, result.Err()
+}
+
+// prepareHandles optionally normalizes and deduplicates the handles, dropping empty ones
+func prepareHandles(format NotificationFormat, deviceHandles []string, normalize, deduplicate bool) []string {
+	var (
+		handles = make([]string, 0, len(deviceHandles))
+		seen    = map[string]bool{}
+	)
+	for _, handle := range deviceHandles {
+		if normalize {
+			handle = normalizeHandle(format, handle)
+		}
+		if handle == "" {
+			continue
+		}
+		if deduplicate {
+			if seen[handle] {
+				continue
+			}
+			seen[handle] = true
+		}
+		handles = append(handles, handle)
+	}
+	return handles
+}
+
+// normalizeHandle trims the handle, Apple device tokens are also
+// stripped of the brackets and spaces of their NSData description and lower cased
+func normalizeHandle(format NotificationFormat, handle string) string {
+	handle = strings.TrimSpace(handle)
+	if format == AppleFormat {
+		handle = strings.NewReplacer("<", "", ">", "", " ", "").Replace(handle)
+		handle = strings.ToLower(handle)
+	}
+	return handle
+}
@@ -0,0 +1,142 @@
+package notificationhubs_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	. "github.com/daresaydigital/azure-notificationhubs-go"
+)
+
+type mockLimiter struct {
+	mu    sync.Mutex
+	waits int
+	err   error
+}
+
+func (l *mockLimiter) Wait(ctx context.Context) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.waits++
+	return l.err
+}
+
+// batchHandles reads the device handles from a batch request
+func batchHandles(t *testing.T, req *http.Request) []string {
+	_, params, err := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	if err != nil {
+		t.Fatalf(errfmt, "Content-Type", "multipart", err)
+	}
+	reader := multipart.NewReader(req.Body, params["boundary"])
+	for {
+		part, err := reader.NextPart()
+		if err != nil {
+			t.Fatalf(errfmt, "devices part", "found", err)
+		}
+		if strings.Contains(part.Header.Get("Content-Disposition"), "name=devices") {
+			var handles []string
+			data, _ := ioutil.ReadAll(part)
+			if err = json.Unmarshal(data, &handles); err != nil {
+				t.Fatalf(errfmt, "devices", "JSON array", err)
+			}
+			return handles
+		}
+	}
+}
+
+func Test_SendDirectBulk(t *testing.T) {
+	var (
+		nhub, mockClient = initTestItems()
+		notification, _  = NewNotification(AppleFormat, []byte(`{"aps":{"alert":"hello"}}`))
+		limiter          = &mockLimiter{}
+		handles          []string
+		mu               sync.Mutex
+		batchSizes       = map[int]int{}
+	)
+	for i := 0; i < 2500; i++ {
+		handles = append(handles, fmt.Sprintf(" <%08X> ", i))
+	}
+	handles = append(handles, "<00000000>", "", "  ")
+
+	mockClient.execFunc = func(req *http.Request) ([]byte, *http.Response, error) {
+		got := batchHandles(t, req)
+		mu.Lock()
+		batchSizes[len(got)]++
+		mu.Unlock()
+		if got[0] != strings.ToLower(got[0]) || strings.ContainsAny(got[0], "<> ") {
+			t.Errorf(errfmt, "normalized handle", strings.ToLower(strings.Trim(got[0], "<> ")), got[0])
+		}
+		mockResponse := http.Response{
+			Header: http.Header{
+				"Location": []string{
+					fmt.Sprintf("https://messages.servicebus.windows.net/messagebus/messages/%s?api-version=2016-10", got[0]),
+				},
+			},
+		}
+		return nil, &mockResponse, nil
+	}
+
+	result, err := nhub.SendDirectBulk(context.Background(), notification, handles, &DirectBulkOptions{
+		Concurrency: 2,
+		Limiter:     limiter,
+		Deduplicate: true,
+		Normalize:   true,
+	})
+	if err != nil {
+		t.Fatalf(errfmt, "error", nil, err)
+	}
+	if expected := map[int]int{1000: 2, 500: 1}; !reflect.DeepEqual(batchSizes, expected) {
+		t.Errorf(errfmt, "batch sizes", expected, batchSizes)
+	}
+	if expected := []string{"00000000", "000003e8", "000007d0"}; !reflect.DeepEqual(result.MessageIDs(), expected) {
+		t.Errorf(errfmt, "message IDs", expected, result.MessageIDs())
+	}
+	if limiter.waits != 3 {
+		t.Errorf(errfmt, "limiter waits", 3, limiter.waits)
+	}
+}
+
+func Test_SendDirectBulkError(t *testing.T) {
+	var (
+		nhub, notification, mockClient = initNotificationTestItems()
+		expectedError                  = errors.New("rate limited")
+		limiter                        = &mockLimiter{err: expectedError}
+	)
+
+	mockClient.execFunc = func(req *http.Request) ([]byte, *http.Response, error) {
+		t.Errorf("unexpected request to %s", req.URL)
+		return nil, nil, nil
+	}
+
+	result, err := nhub.SendDirectBulk(context.Background(), notification, []string{"a", "b", "c"}, &DirectBulkOptions{
+		BatchSize: 2,
+		Limiter:   limiter,
+	})
+	if err == nil || !strings.Contains(err.Error(), expectedError.Error()) {
+		t.Errorf(errfmt, "SendDirectBulk error", expectedError, err)
+	}
+	if failed := result.Failed(); len(failed) != 2 {
+		t.Errorf(errfmt, "failed batches", 2, len(failed))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	limiter.err = nil
+	result, err = nhub.SendDirectBulk(ctx, notification, []string{"a"}, nil)
+	if err == nil || result.Batches[0].Err != context.Canceled {
+		t.Errorf(errfmt, "SendDirectBulk error", context.Canceled, err)
+	}
+
+	if _, err = nhub.SendDirectBulk(context.Background(), notification, []string{" "}, &DirectBulkOptions{Normalize: true}); err == nil {
+		t.Errorf(errfmt, "SendDirectBulk error", "no device handles to send to", err)
+	}
+}
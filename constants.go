@@ -16,6 +16,8 @@ const (
 	AppleTemplatePlatform        TargetPlatform = "appletemplate"
 	BaiduPlatform                TargetPlatform = "baidu"
 	BaiduTemplatePlatform        TargetPlatform = "baidutemplate"
+	BrowserPlatform              TargetPlatform = "browser"
+	FcmV1Platform                TargetPlatform = "fcmv1"
 	GcmPlatform                  TargetPlatform = "gcm"
 	GcmTemplatePlatform          TargetPlatform = "gcmtemplate"
 	TemplatePlatform             TargetPlatform = "template"
@@ -23,6 +25,7 @@ const (
 	WindowsphoneTemplatePlatform TargetPlatform = "windowsphonetemplate"
 	WindowsPlatform              TargetPlatform = "windows"
 	WindowsTemplatePlatform      TargetPlatform = "windowstemplate"
+	XiaomiPlatform               TargetPlatform = "xiaomi"
 
 	APNSPlatform InstallationPlatform = "apns"
 	WNSPlatform  InstallationPlatform = "wns"
@@ -30,6 +33,17 @@ const (
 	ADMPlatform  InstallationPlatform = "adm"
 	GCMPlatform  InstallationPlatform = "gcm"
 
+	ExportRegistrationsJob       NotificationHubJobType = "ExportRegistrations"
+	ImportCreateRegistrationsJob NotificationHubJobType = "ImportCreateRegistrations"
+	ImportUpdateRegistrationsJob NotificationHubJobType = "ImportUpdateRegistrations"
+	ImportDeleteRegistrationsJob NotificationHubJobType = "ImportDeleteRegistrations"
+	ImportUpsertRegistrationsJob NotificationHubJobType = "ImportUpsertRegistrations"
+
+	JobStarted   NotificationHubJobStatus = "Started"
+	JobRunning   NotificationHubJobStatus = "Running"
+	JobCompleted NotificationHubJobStatus = "Completed"
+	JobFailed    NotificationHubJobStatus = "Failed"
+
 	InstallationChangeAdd     InstallationChangeOp = "add"
 	InstallationChangeRemove  InstallationChangeOp = "remove"
 	InstallationChangeReplace InstallationChangeOp = "replace"
@@ -57,6 +71,9 @@ const (
 	AbandonedNotificationMessages NotificationOutcomeName = "AbandonedNotificationMessages"
 	// BadChannel: Communication to the push service failed because the channel was invalid.
 	BadChannel NotificationOutcomeName = "BadChannel"
+	// CanceledNotification: The scheduled notification was canceled before it was sent.
+	// It is not reported by the hub, DirectBatchOutcome uses it for the handles of a Canceled notification.
+	CanceledNotification NotificationOutcomeName = "Canceled"
 	// ChannelDisconnected: Push service disconnected.
 	ChannelDisconnected NotificationOutcomeName = "ChannelDisconnected"
 	// ChannelThrottled: Push service denied access due to throttling.
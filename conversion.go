@@ -0,0 +1,297 @@
+package notificationhubs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/daresaydigital/azure-notificationhubs-go/utils"
+)
+
+type (
+	// RegistrationConversion is an installation equivalent to the registrations of one device
+	RegistrationConversion struct {
+		Installation  Installation          `json:"installation"`
+		Registrations []RegistrationContent `json:"registrations"`
+	}
+
+	// InstallationMigrationOptions configures MigrateToInstallations
+	InstallationMigrationOptions struct {
+		// RollbackLog receives a JSON line for every conversion before it is installed, or nil
+		RollbackLog io.Writer
+		// Concurrency is the maximum number of devices migrated at once, defaults to 4
+		Concurrency int
+	}
+
+	// InstallationMigrationResult lists the migrated and failed installations by installation ID
+	InstallationMigrationResult struct {
+		Installed []string
+		Failed    map[string]error
+	}
+
+	// rollbackEntry is a line of the rollback log
+	rollbackEntry struct {
+		Time time.Time `json:"time"`
+		RegistrationConversion
+	}
+)
+
+// ConvertRegistrations groups the registrations by device and converts each device to an installation
+// Native registration tags become installation tags, template registrations become named templates
+// with their tags. The installation ID is generated by idFunc or, if nil, is the first registration ID.
+// Only Apple and GCM registrations, which RollbackInstallations can register again, are converted,
+// the registrations of other platforms are returned as skipped and are left untouched
+func ConvertRegistrations(registrations []RegistrationResult, idFunc func(platform InstallationPlatform, pushChannel string) string) (conversions []*RegistrationConversion, skipped []RegistrationResult) {
+	byDevice := map[string]*RegistrationConversion{}
+
+	for _, r := range registrations {
+		content := r.RegistrationContent
+		if content == nil || content.RegisteredDevice == nil {
+			continue
+		}
+		device := content.RegisteredDevice
+
+		platform, isTemplate, ok := installationPlatform(content.Target)
+		if !ok {
+			skipped = append(skipped, r)
+			continue
+		}
+
+		key := string(platform) + ":" + device.DeviceID
+		conversion, ok := byDevice[key]
+		if !ok {
+			installationID := device.RegistrationID
+			if idFunc != nil {
+				installationID = idFunc(platform, device.DeviceID)
+			}
+			conversion = &RegistrationConversion{
+				Installation: Installation{
+					InstallationID: installationID,
+					Platform:       platform,
+					PushChannel:    device.DeviceID,
+				},
+			}
+			byDevice[key] = conversion
+			conversions = append(conversions, conversion)
+		}
+		conversion.Registrations = append(conversion.Registrations, *content)
+
+		installation := &conversion.Installation
+		if !isTemplate {
+			installation.Tags = mergeTags(installation.Tags, device.Tags)
+			continue
+		}
+		if installation.Templates == nil {
+			installation.Templates = map[string]InstallationTemplate{}
+		}
+		name := device.TemplateName
+		if name == "" {
+			name = "template" + strconv.Itoa(len(installation.Templates)+1)
+		}
+		template := installation.Templates[name]
+		template.Body = device.Template
+		template.Tags = mergeTags(template.Tags, device.Tags)
+		installation.Templates[name] = template
+	}
+	return conversions, skipped
+}
+
+// MigrateToInstallations installs every conversion and unregisters its registrations once the install succeeded
+// The registrations are only removed after the installation is in place, so devices keep receiving notifications
+func (h *NotificationHub) MigrateToInstallations(ctx context.Context, conversions []*RegistrationConversion, opts *InstallationMigrationOptions) (*InstallationMigrationResult, error) {
+	if opts == nil {
+		opts = &InstallationMigrationOptions{}
+	}
+
+	var (
+		mu     sync.Mutex
+		result = &InstallationMigrationResult{Failed: map[string]error{}}
+	)
+
+	forEachParallel(len(conversions), opts.Concurrency, func(i int) {
+		conversion := conversions[i]
+		err := h.migrateToInstallation(ctx, conversion, opts.RollbackLog, &mu)
+
+		mu.Lock()
+		defer mu.Unlock()
+		if err != nil {
+			result.Failed[conversion.Installation.InstallationID] = err
+			return
+		}
+		result.Installed = append(result.Installed, conversion.Installation.InstallationID)
+	})
+	sort.Strings(result.Installed)
+
+	if len(result.Failed) > 0 {
+		return result, fmt.Errorf("%d installations could not be migrated", len(result.Failed))
+	}
+	return result, nil
+}
+
+// migrateToInstallation logs, installs and unregisters a single conversion
+// The mutex guards the rollback log
+func (h *NotificationHub) migrateToInstallation(ctx context.Context, conversion *RegistrationConversion, rollbackLog io.Writer, mu *sync.Mutex) error {
+	if rollbackLog != nil {
+		line, err := json.Marshal(rollbackEntry{Time: time.Now().UTC(), RegistrationConversion: *conversion})
+		if err != nil {
+			return err
+		}
+		mu.Lock()
+		_, err = rollbackLog.Write(append(line, '\n'))
+		mu.Unlock()
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := h.Install(ctx, conversion.Installation); err != nil {
+		return err
+	}
+
+	var failed []string
+	for _, content := range conversion.Registrations {
+		device := *content.RegisteredDevice
+		if device.ETag == "" {
+			device.ETag = "*"
+		}
+		if err := h.Unregister(ctx, device); err != nil {
+			failed = append(failed, fmt.Sprintf("%s: %v", device.RegistrationID, err))
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("installed but could not unregister %s", strings.Join(failed, "; "))
+	}
+	return nil
+}
+
+// RollbackInstallations reads a rollback log written by MigrateToInstallations,
+// registers the original registrations again and removes the installations
+// An installation is only removed once all of its registrations were restored, so a device is never left
+// without either. Failed entries are skipped and reported in the returned error, the registrations restored
+// before the failure are kept. A registration still in the hub, because its installation or removal failed,
+// is left as is, the others are restored with their original registration IDs, so the log can be replayed
+// without duplicating registrations
+func (h *NotificationHub) RollbackInstallations(ctx context.Context, r io.Reader) error {
+	var (
+		decoder = json.NewDecoder(r)
+		failed  []string
+	)
+	for {
+		var entry rollbackEntry
+		if err := decoder.Decode(&entry); err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+
+		if err := h.rollbackInstallation(ctx, entry.RegistrationConversion); err != nil {
+			failed = append(failed, fmt.Sprintf("%s: %v", entry.Installation.InstallationID, err))
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("could not roll back %s", strings.Join(failed, "; "))
+	}
+	return nil
+}
+
+// rollbackInstallation restores the registrations of a single conversion and then removes its installation
+func (h *NotificationHub) rollbackInstallation(ctx context.Context, conversion RegistrationConversion) error {
+	for _, content := range conversion.Registrations {
+		if err := h.restoreRegistration(ctx, content); err != nil {
+			return err
+		}
+	}
+	return h.Uninstall(ctx, conversion.Installation.InstallationID)
+}
+
+// restoreRegistration registers a logged registration again with its original ID, unless it still exists
+func (h *NotificationHub) restoreRegistration(ctx context.Context, content RegistrationContent) error {
+	if content.RegisteredDevice == nil {
+		return nil
+	}
+	var (
+		device = content.RegisteredDevice
+		tags   = strings.Join(device.Tags, ",")
+	)
+	if _, _, ok := installationPlatform(content.Target); !ok {
+		return fmt.Errorf("registration %s: unsupported platform %s", device.RegistrationID, content.Target)
+	}
+
+	_, _, err := h.Registration(ctx, device.RegistrationID)
+	var httpErr *utils.HTTPError
+	switch {
+	case err == nil:
+		return nil
+	case !errors.As(err, &httpErr) || httpErr.StatusCode != http.StatusNotFound:
+		return fmt.Errorf("registration %s: %w", device.RegistrationID, err)
+	}
+
+	switch content.Target {
+	case ApplePlatform, GcmPlatform:
+		_, _, err = h.Register(ctx, Registration{
+			DeviceID:           device.DeviceID,
+			NotificationFormat: content.Format,
+			RegistrationID:     device.RegistrationID,
+			Tags:               tags,
+		})
+	case AppleTemplatePlatform:
+		_, _, err = h.RegisterWithTemplate(ctx, TemplateRegistration{
+			DeviceID:       device.DeviceID,
+			RegistrationID: device.RegistrationID,
+			Tags:           tags,
+			Platform:       ApplePlatform,
+			Template:       device.Template,
+		})
+	case GcmTemplatePlatform:
+		_, _, err = h.RegisterWithTemplate(ctx, TemplateRegistration{
+			DeviceID:       device.DeviceID,
+			RegistrationID: device.RegistrationID,
+			Tags:           tags,
+			Platform:       GcmPlatform,
+			Template:       device.Template,
+		})
+	}
+	return err
+}
+
+// installationPlatform maps a registration target to the installation platform
+// and identifies template registrations
+// Only the targets restoreRegistration can register again are supported
+func installationPlatform(target TargetPlatform) (platform InstallationPlatform, isTemplate bool, ok bool) {
+	switch target {
+	case ApplePlatform:
+		return APNSPlatform, false, true
+	case AppleTemplatePlatform:
+		return APNSPlatform, true, true
+	case GcmPlatform:
+		return GCMPlatform, false, true
+	case GcmTemplatePlatform:
+		return GCMPlatform, true, true
+	}
+	return "", false, false
+}
+
+// mergeTags appends the tags not yet present
+func mergeTags(tags, add []string) []string {
+	for _, tag := range add {
+		found := false
+		for _, existing := range tags {
+			if existing == tag {
+				found = true
+				break
+			}
+		}
+		if !found {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
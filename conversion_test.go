@@ -0,0 +1,245 @@
+package notificationhubs_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	. "github.com/daresaydigital/azure-notificationhubs-go"
+	"github.com/daresaydigital/azure-notificationhubs-go/utils"
+)
+
+func conversionRegistrations() []RegistrationResult {
+	return []RegistrationResult{
+		{RegistrationContent: &RegistrationContent{
+			Format: AppleFormat,
+			Target: ApplePlatform,
+			RegisteredDevice: &RegisteredDevice{
+				RegistrationID: "reg-1",
+				DeviceID:       "ABCDEFG",
+				ETag:           "1",
+				Tags:           []string{"tag1", "tag2"},
+			},
+		}},
+		{RegistrationContent: &RegistrationContent{
+			Format: Template,
+			Target: AppleTemplatePlatform,
+			RegisteredDevice: &RegisteredDevice{
+				RegistrationID: "reg-2",
+				DeviceID:       "ABCDEFG",
+				Tags:           []string{"tag3"},
+				Template:       `{"aps":{"alert":"$(message)"}}`,
+				TemplateName:   "alert",
+			},
+		}},
+		{RegistrationContent: &RegistrationContent{
+			Format: GcmFormat,
+			Target: GcmPlatform,
+			RegisteredDevice: &RegisteredDevice{
+				RegistrationID: "reg-3",
+				DeviceID:       "ANDROIDID",
+				Tags:           []string{"tag1"},
+			},
+		}},
+	}
+}
+
+func TestConvertRegistrations(t *testing.T) {
+	conversions, skipped := ConvertRegistrations(conversionRegistrations(), nil)
+	if len(skipped) != 0 {
+		t.Errorf(errfmt, "skipped", 0, len(skipped))
+	}
+	if len(conversions) != 2 {
+		t.Fatalf(errfmt, "conversions", 2, len(conversions))
+	}
+
+	want := Installation{
+		InstallationID: "reg-1",
+		Platform:       APNSPlatform,
+		PushChannel:    "ABCDEFG",
+		Tags:           []string{"tag1", "tag2"},
+		Templates: map[string]InstallationTemplate{
+			"alert": {
+				Body: `{"aps":{"alert":"$(message)"}}`,
+				Tags: []string{"tag3"},
+			},
+		},
+	}
+	if !reflect.DeepEqual(conversions[0].Installation, want) {
+		t.Errorf(errfmt, "apple installation", want, conversions[0].Installation)
+	}
+	if len(conversions[0].Registrations) != 2 {
+		t.Errorf(errfmt, "apple registrations", 2, len(conversions[0].Registrations))
+	}
+
+	want = Installation{
+		InstallationID: "reg-3",
+		Platform:       GCMPlatform,
+		PushChannel:    "ANDROIDID",
+		Tags:           []string{"tag1"},
+	}
+	if !reflect.DeepEqual(conversions[1].Installation, want) {
+		t.Errorf(errfmt, "gcm installation", want, conversions[1].Installation)
+	}
+
+	conversions, _ = ConvertRegistrations(conversionRegistrations(), func(platform InstallationPlatform, pushChannel string) string {
+		return string(platform) + "-" + pushChannel
+	})
+	if conversions[0].Installation.InstallationID != "apns-ABCDEFG" {
+		t.Errorf(errfmt, "InstallationID", "apns-ABCDEFG", conversions[0].Installation.InstallationID)
+	}
+
+	registrations := append(conversionRegistrations(),
+		RegistrationResult{RegistrationContent: &RegistrationContent{
+			Target:           BaiduPlatform,
+			RegisteredDevice: &RegisteredDevice{RegistrationID: "reg-4", DeviceID: "BAIDUID"},
+		}},
+		RegistrationResult{RegistrationContent: &RegistrationContent{
+			Target:           WindowsPlatform,
+			RegisteredDevice: &RegisteredDevice{RegistrationID: "reg-5", DeviceID: "WNSURI"},
+		}},
+	)
+	conversions, skipped = ConvertRegistrations(registrations, nil)
+	if len(conversions) != 2 {
+		t.Errorf(errfmt, "conversions with unsupported platforms", 2, len(conversions))
+	}
+	if len(skipped) != 2 || skipped[0].RegistrationContent.RegisteredDevice.RegistrationID != "reg-4" ||
+		skipped[1].RegistrationContent.RegisteredDevice.RegistrationID != "reg-5" {
+		t.Errorf(errfmt, "skipped", "reg-4 and reg-5", skipped)
+	}
+}
+
+func Test_MigrateToInstallations(t *testing.T) {
+	var (
+		nhub, mockClient = initTestItems()
+		conversions, _   = ConvertRegistrations(conversionRegistrations(), nil)
+		rollbackLog      bytes.Buffer
+		mu               sync.Mutex
+		requests         []string
+	)
+
+	mockClient.execFunc = func(req *http.Request) ([]byte, *http.Response, error) {
+		mu.Lock()
+		requests = append(requests, req.Method+" "+req.URL.Path+" "+req.Header.Get("If-Match"))
+		mu.Unlock()
+		if req.Method == deleteMethod && strings.HasSuffix(req.URL.Path, "/reg-3") {
+			return nil, nil, errors.New("test error")
+		}
+		return nil, nil, nil
+	}
+
+	result, err := nhub.MigrateToInstallations(context.Background(), conversions, &InstallationMigrationOptions{
+		RollbackLog: &rollbackLog,
+	})
+	if err == nil {
+		t.Errorf(errfmt, "MigrateToInstallations error", "unregister failure", err)
+	}
+	if !reflect.DeepEqual(result.Installed, []string{"reg-1"}) {
+		t.Errorf(errfmt, "Installed", []string{"reg-1"}, result.Installed)
+	}
+	if err := result.Failed["reg-3"]; err == nil || !strings.Contains(err.Error(), "installed but could not unregister reg-3") {
+		t.Errorf(errfmt, "Failed", "reg-3 unregister error", err)
+	}
+
+	sort.Strings(requests)
+	wantRequests := []string{
+		"DELETE /testhub/registrations/reg-1 1",
+		"DELETE /testhub/registrations/reg-2 *",
+		"DELETE /testhub/registrations/reg-3 *",
+		"PUT /testhub/installations/reg-1 ",
+		"PUT /testhub/installations/reg-3 ",
+	}
+	if !reflect.DeepEqual(requests, wantRequests) {
+		t.Errorf(errfmt, "requests", wantRequests, requests)
+	}
+	if lines := strings.Count(rollbackLog.String(), "\n"); lines != 2 {
+		t.Errorf(errfmt, "rollback log lines", 2, lines)
+	}
+
+	// reg-3 could not be unregistered, so only reg-1 and reg-2 are restored, with their own IDs
+	requests = nil
+	mockClient.execFunc = func(req *http.Request) ([]byte, *http.Response, error) {
+		requests = append(requests, req.Method+" "+req.URL.Path)
+		switch {
+		case req.Method == getMethod && strings.HasSuffix(req.URL.Path, "/reg-3"):
+			return []byte(gcmRegistrationResultXML), nil, nil
+		case req.Method == getMethod:
+			return nil, nil, &utils.HTTPError{StatusCode: http.StatusNotFound}
+		case req.Method == putMethod:
+			return []byte(emptyRegistrationResultXML), nil, nil
+		}
+		return nil, nil, nil
+	}
+	if err := nhub.RollbackInstallations(context.Background(), &rollbackLog); err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(requests)
+	wantRequests = []string{
+		"DELETE /testhub/installations/reg-1",
+		"DELETE /testhub/installations/reg-3",
+		"GET /testhub/registrations/reg-1",
+		"GET /testhub/registrations/reg-2",
+		"GET /testhub/registrations/reg-3",
+		"PUT /testhub/registrations/reg-1",
+		"PUT /testhub/registrations/reg-2",
+	}
+	if !reflect.DeepEqual(requests, wantRequests) {
+		t.Errorf(errfmt, "rollback requests", wantRequests, requests)
+	}
+}
+
+const (
+	// emptyRegistrationResultXML is a registration entry without a registration description
+	emptyRegistrationResultXML = `<entry xmlns="http://www.w3.org/2005/Atom"><content type="application/xml"></content></entry>`
+	// gcmRegistrationResultXML is the registration entry of reg-3
+	gcmRegistrationResultXML = `<entry xmlns="http://www.w3.org/2005/Atom"><content type="application/xml"><GcmRegistrationDescription xmlns="http://schemas.microsoft.com/netservices/2010/10/servicebus/connect"><RegistrationId>reg-3</RegistrationId><GcmRegistrationId>ANDROIDID</GcmRegistrationId></GcmRegistrationDescription></content></entry>`
+)
+
+func Test_RollbackInstallationsRestoresFirst(t *testing.T) {
+	var (
+		nhub, mockClient = initTestItems()
+		rollbackLog      bytes.Buffer
+		requests         []string
+	)
+
+	// a device whose restore fails, a device on a platform that cannot be restored and a device restored normally
+	rollbackLog.WriteString(`{"installation":{"installationId":"gcm-1","platform":"gcm","pushChannel":"ANDROIDID"},"registrations":[{"Format":"gcm","Target":"gcm","RegisteredDevice":{"RegistrationID":"reg-1","DeviceID":"ANDROIDID"}},{"Format":"gcm","Target":"gcm","RegisteredDevice":{"RegistrationID":"reg-2","DeviceID":"FAILING"}}]}` + "\n")
+	rollbackLog.WriteString(`{"installation":{"installationId":"wns-1","platform":"wns","pushChannel":"WNSURI"},"registrations":[{"Format":"windows","Target":"windows","RegisteredDevice":{"RegistrationID":"reg-3","DeviceID":"WNSURI"}}]}` + "\n")
+	rollbackLog.WriteString(`{"installation":{"installationId":"apns-1","platform":"apns","pushChannel":"ABCDEFG"},"registrations":[{"Format":"apple","Target":"apple","RegisteredDevice":{"RegistrationID":"reg-4","DeviceID":"ABCDEFG"}}]}` + "\n")
+
+	mockClient.execFunc = func(req *http.Request) ([]byte, *http.Response, error) {
+		switch req.Method {
+		case getMethod:
+			return nil, nil, &utils.HTTPError{StatusCode: http.StatusNotFound}
+		case putMethod:
+			body, _ := ioutil.ReadAll(req.Body)
+			if strings.Contains(string(body), "FAILING") {
+				return nil, nil, errors.New("test error")
+			}
+			requests = append(requests, req.Method+" "+req.URL.Path)
+			return []byte(emptyRegistrationResultXML), nil, nil
+		}
+		requests = append(requests, req.Method+" "+req.URL.Path)
+		return nil, nil, nil
+	}
+
+	err := nhub.RollbackInstallations(context.Background(), &rollbackLog)
+	if err == nil || !strings.Contains(err.Error(), "gcm-1: ") || !strings.Contains(err.Error(), "wns-1: ") {
+		t.Errorf(errfmt, "RollbackInstallations error", "gcm-1 and wns-1 failures", err)
+	}
+	wantRequests := []string{
+		"PUT /testhub/registrations/reg-1",
+		"PUT /testhub/registrations/reg-4",
+		"DELETE /testhub/installations/apns-1",
+	}
+	if !reflect.DeepEqual(requests, wantRequests) {
+		t.Errorf(errfmt, "rollback requests", wantRequests, requests)
+	}
+}
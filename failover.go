@@ -0,0 +1,275 @@
+package notificationhubs
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/daresaydigital/azure-notificationhubs-go/utils"
+)
+
+const (
+	defaultFailoverThreshold = 3
+	defaultFailoverCooldown  = 30 * time.Second
+)
+
+// ErrNoHealthyHub is returned by a FailoverHub when every hub is cooling down
+var ErrNoHealthyHub = errors.New("no healthy notification hub")
+
+type (
+	// FailoverOptions configures a FailoverHub
+	FailoverOptions struct {
+		// FailureThreshold is the number of consecutive failures marking a hub unhealthy, defaults to 3
+		FailureThreshold int
+		// Cooldown is the time an unhealthy hub is skipped before it is probed, defaults to 30 seconds
+		Cooldown time.Duration
+		// Probe checks whether a hub recovered, defaults to reading a single registration
+		Probe func(ctx context.Context, h *NotificationHub) error
+		// RetryTimeouts fails over to the next hub when a call times out
+		// A hub which timed out may still have accepted the call, so a retried send can be delivered twice.
+		// By default a timeout only counts as a failure of the hub and is returned to the caller
+		RetryTimeouts bool
+	}
+
+	// FailoverHubStatus is the health of a hub of a FailoverHub
+	FailoverHubStatus struct {
+		Hub                 *NotificationHub
+		Healthy             bool
+		ConsecutiveFailures int
+		UnhealthyUntil      time.Time
+	}
+
+	// FailoverHub routes calls to the first healthy hub of an ordered list,
+	// typically hubs in different namespaces and regions
+	// A hub is unhealthy after consecutive 5xx responses or timeouts and is probed after a cool-down.
+	// Calls failing with a timeout are only retried on the next hub with RetryTimeouts
+	FailoverHub struct {
+		hubs []*failoverMember
+		opts FailoverOptions
+	}
+
+	// failoverMember tracks the health of a hub
+	failoverMember struct {
+		mu             sync.Mutex
+		hub            *NotificationHub
+		failures       int
+		unhealthyUntil time.Time
+		probing        bool
+	}
+)
+
+// NewFailoverHub initializes and returns FailoverHub pointer, hubs are tried in order
+func NewFailoverHub(hubs []*NotificationHub, opts *FailoverOptions) *FailoverHub {
+	f := &FailoverHub{}
+	if opts != nil {
+		f.opts = *opts
+	}
+	if f.opts.FailureThreshold < 1 {
+		f.opts.FailureThreshold = defaultFailoverThreshold
+	}
+	if f.opts.Cooldown <= 0 {
+		f.opts.Cooldown = defaultFailoverCooldown
+	}
+	if f.opts.Probe == nil {
+		f.opts.Probe = probeHub
+	}
+	for _, hub := range hubs {
+		f.hubs = append(f.hubs, &failoverMember{hub: hub})
+	}
+	return f
+}
+
+// Do calls fn with the first healthy hub, failing over to the next hub on 5xx responses
+// and, with RetryTimeouts, on timeouts
+// It returns the hub which handled the call, the hub which timed out, or nil if no hub could
+func (f *FailoverHub) Do(ctx context.Context, fn func(h *NotificationHub) error) (*NotificationHub, error) {
+	return f.do(ctx, func(_ context.Context, h *NotificationHub) error {
+		return fn(h)
+	})
+}
+
+// do calls fn with the first healthy hub and a context marking the retry attempts after a failover
+func (f *FailoverHub) do(ctx context.Context, fn func(ctx context.Context, h *NotificationHub) error) (*NotificationHub, error) {
+	var (
+		err     = ErrNoHealthyHub
+		attempt = 0
+	)
+	for _, member := range f.hubs {
+		if !f.available(ctx, member) {
+			continue
+		}
+		attempt++
+		err = fn(WithRetryAttempt(ctx, attempt), member.hub)
+		switch {
+		case ctx.Err() != nil:
+			// the caller gave up, which says nothing about the health of the hub
+			return member.hub, err
+		case !isFailoverError(ctx, err):
+			member.succeeded()
+			return member.hub, err
+		}
+		member.failed(f.opts.FailureThreshold, f.opts.Cooldown)
+		if isTimeoutError(err) && !f.opts.RetryTimeouts {
+			return member.hub, err
+		}
+	}
+	return nil, err
+}
+
+// Send publishes a notification through the first healthy hub and returns the hub which handled it
+func (f *FailoverHub) Send(ctx context.Context, n *Notification, tags *string) (raw []byte, telemetry *NotificationTelemetry, handledBy *NotificationHub, err error) {
+	handledBy, err = f.do(ctx, func(ctx context.Context, h *NotificationHub) (e error) {
+		raw, telemetry, e = h.Send(ctx, n, tags)
+		return
+	})
+	return
+}
+
+// SendDirect publishes a notification directly to a device through the first healthy hub
+// and returns the hub which handled it
+func (f *FailoverHub) SendDirect(ctx context.Context, n *Notification, deviceHandle string) (raw []byte, telemetry *NotificationTelemetry, handledBy *NotificationHub, err error) {
+	handledBy, err = f.do(ctx, func(ctx context.Context, h *NotificationHub) (e error) {
+		raw, telemetry, e = h.SendDirect(ctx, n, deviceHandle)
+		return
+	})
+	return
+}
+
+// Schedule schedules a notification through the first healthy hub and returns the hub which handled it
+func (f *FailoverHub) Schedule(ctx context.Context, n *Notification, tags *string, deliverTime time.Time) (raw []byte, telemetry *NotificationTelemetry, handledBy *NotificationHub, err error) {
+	handledBy, err = f.do(ctx, func(ctx context.Context, h *NotificationHub) (e error) {
+		raw, telemetry, e = h.Schedule(ctx, n, tags, deliverTime)
+		return
+	})
+	return
+}
+
+// Probe probes every unhealthy hub, whether or not its cool-down has passed,
+// and marks the recovered hubs healthy
+func (f *FailoverHub) Probe(ctx context.Context) {
+	for _, member := range f.hubs {
+		member.mu.Lock()
+		unhealthy := !member.unhealthyUntil.IsZero()
+		member.mu.Unlock()
+		if unhealthy {
+			f.probe(ctx, member)
+		}
+	}
+}
+
+// Status returns the health of every hub, in order
+func (f *FailoverHub) Status() []FailoverHubStatus {
+	status := make([]FailoverHubStatus, len(f.hubs))
+	for i, member := range f.hubs {
+		member.mu.Lock()
+		status[i] = FailoverHubStatus{
+			Hub:                 member.hub,
+			Healthy:             member.unhealthyUntil.IsZero(),
+			ConsecutiveFailures: member.failures,
+			UnhealthyUntil:      member.unhealthyUntil,
+		}
+		member.mu.Unlock()
+	}
+	return status
+}
+
+// available identifies whether a hub can be called, probing it once its cool-down passed
+func (f *FailoverHub) available(ctx context.Context, member *failoverMember) bool {
+	member.mu.Lock()
+	unhealthyUntil := member.unhealthyUntil
+	member.mu.Unlock()
+
+	switch {
+	case unhealthyUntil.IsZero():
+		return true
+	case time.Now().Before(unhealthyUntil):
+		return false
+	default:
+		return f.probe(ctx, member)
+	}
+}
+
+// probe marks a hub healthy if the probe succeeds, or extends its cool-down
+// Only one probe of a hub runs at a time, the hub is skipped by the other callers until it finished
+func (f *FailoverHub) probe(ctx context.Context, member *failoverMember) bool {
+	member.mu.Lock()
+	if member.probing {
+		member.mu.Unlock()
+		return false
+	}
+	member.probing = true
+	member.mu.Unlock()
+	defer func() {
+		member.mu.Lock()
+		member.probing = false
+		member.mu.Unlock()
+	}()
+
+	err := f.opts.Probe(ctx, member.hub)
+	if ctx.Err() != nil {
+		return false
+	}
+	if isFailoverError(ctx, err) {
+		member.mu.Lock()
+		member.unhealthyUntil = time.Now().Add(f.opts.Cooldown)
+		member.mu.Unlock()
+		return false
+	}
+	member.succeeded()
+	return true
+}
+
+// succeeded resets the failures of a hub
+func (m *failoverMember) succeeded() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.failures = 0
+	m.unhealthyUntil = time.Time{}
+}
+
+// failed counts a failure and starts the cool-down at the threshold
+func (m *failoverMember) failed(threshold int, cooldown time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.failures++
+	if m.failures >= threshold {
+		m.unhealthyUntil = time.Now().Add(cooldown)
+	}
+}
+
+// isFailoverError identifies errors caused by an unavailable hub: 5xx responses, timeouts and open circuits
+// The expiry or cancellation of the caller's context is not a failure of the hub
+func isFailoverError(ctx context.Context, err error) bool {
+	if err == nil || ctx.Err() != nil {
+		return false
+	}
+	if errors.Is(err, utils.ErrCircuitOpen) {
+		return true
+	}
+	var httpErr *utils.HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.StatusCode >= 500
+	}
+	return isTimeoutError(err)
+}
+
+// isTimeoutError identifies timeouts, after which the hub may have accepted the request
+func isTimeoutError(err error) bool {
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	return errors.Is(err, context.DeadlineExceeded)
+}
+
+// probeHub reads a single registration to check the hub responds
+func probeHub(ctx context.Context, h *NotificationHub) error {
+	regURL := h.generateAPIURL("registrations")
+	query := regURL.Query()
+	query.Set("$top", "1")
+	regURL.RawQuery = query.Encode()
+	_, _, err := h.exec(ctx, "Probe", getMethod, regURL, Headers{}, nil)
+	return err
+}
@@ -0,0 +1,247 @@
+package notificationhubs_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "github.com/daresaydigital/azure-notificationhubs-go"
+	"github.com/daresaydigital/azure-notificationhubs-go/utils"
+)
+
+// initFailoverTestItems returns a hub answering sends with the status code returned by status
+// and counting its requests
+func initFailoverTestItems(status func() int, requests *int) *NotificationHub {
+	nhub, mockClient := initTestItems()
+	mockClient.execFunc = func(req *http.Request) ([]byte, *http.Response, error) {
+		*requests++
+		if code := status(); code != http.StatusCreated {
+			return nil, &http.Response{StatusCode: code}, &utils.HTTPError{StatusCode: code}
+		}
+		return nil, &http.Response{
+			StatusCode: http.StatusCreated,
+			Header: http.Header{
+				"Location": []string{"https://testhub-ns.servicebus.windows.net/testhub/messages/1?api-version=2016-07"},
+			},
+		}, nil
+	}
+	return nhub
+}
+
+func Test_FailoverHubSend(t *testing.T) {
+	var (
+		primaryStatus                      = http.StatusServiceUnavailable
+		primaryRequests, secondaryRequests int
+		primary                            = initFailoverTestItems(func() int { return primaryStatus }, &primaryRequests)
+		secondary                          = initFailoverTestItems(func() int { return http.StatusCreated }, &secondaryRequests)
+		notification, _                    = NewNotification(Template, []byte("{}"))
+		probes                             int
+		failover                           = NewFailoverHub([]*NotificationHub{primary, secondary}, &FailoverOptions{
+			FailureThreshold: 2,
+			Cooldown:         time.Hour,
+			Probe: func(ctx context.Context, h *NotificationHub) error {
+				probes++
+				if primaryStatus != http.StatusCreated {
+					return &utils.HTTPError{StatusCode: primaryStatus}
+				}
+				return nil
+			},
+		})
+	)
+
+	for i := 0; i < 3; i++ {
+		_, telemetry, handledBy, err := failover.Send(context.Background(), notification, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if handledBy != secondary {
+			t.Errorf(errfmt, "handledBy", "secondary", handledBy)
+		}
+		if telemetry == nil || telemetry.NotificationMessageID != "1" {
+			t.Errorf(errfmt, "telemetry", "1", telemetry)
+		}
+	}
+	if primaryRequests != 2 || secondaryRequests != 3 {
+		t.Errorf(errfmt, "requests", "2 primary and 3 secondary", []int{primaryRequests, secondaryRequests})
+	}
+	if status := failover.Status(); status[0].Healthy || status[0].ConsecutiveFailures != 2 || !status[1].Healthy {
+		t.Errorf(errfmt, "Status", "unhealthy primary", status)
+	}
+
+	failover.Probe(context.Background())
+	if probes != 1 || failover.Status()[0].Healthy {
+		t.Errorf(errfmt, "failed probe", "unhealthy primary", failover.Status()[0])
+	}
+
+	primaryStatus = http.StatusCreated
+	failover.Probe(context.Background())
+	if !failover.Status()[0].Healthy {
+		t.Errorf(errfmt, "probe", "healthy primary", failover.Status()[0])
+	}
+	if _, _, handledBy, _ := failover.Send(context.Background(), notification, nil); handledBy != primary {
+		t.Errorf(errfmt, "handledBy", "primary", handledBy)
+	}
+}
+
+func Test_FailoverHubClientError(t *testing.T) {
+	var (
+		primaryRequests, secondaryRequests int
+		primary                            = initFailoverTestItems(func() int { return http.StatusBadRequest }, &primaryRequests)
+		secondary                          = initFailoverTestItems(func() int { return http.StatusCreated }, &secondaryRequests)
+		failover                           = NewFailoverHub([]*NotificationHub{primary, secondary}, nil)
+		notification, _                    = NewNotification(Template, []byte("{}"))
+	)
+
+	_, _, handledBy, err := failover.Send(context.Background(), notification, nil)
+	var httpErr *utils.HTTPError
+	if !errors.As(err, &httpErr) || httpErr.StatusCode != http.StatusBadRequest {
+		t.Errorf(errfmt, "Send error", http.StatusBadRequest, err)
+	}
+	if handledBy != primary || secondaryRequests != 0 {
+		t.Errorf(errfmt, "handledBy", "primary without failover", handledBy)
+	}
+}
+
+func Test_FailoverHubNoHealthyHub(t *testing.T) {
+	var (
+		requests        int
+		primary         = initFailoverTestItems(func() int { return http.StatusInternalServerError }, &requests)
+		failover        = NewFailoverHub([]*NotificationHub{primary}, &FailoverOptions{FailureThreshold: 1, Cooldown: time.Hour})
+		notification, _ = NewNotification(Template, []byte("{}"))
+	)
+
+	if _, _, _, err := failover.Send(context.Background(), notification, nil); err == nil {
+		t.Errorf(errfmt, "Send error", "server error", err)
+	}
+	if _, _, handledBy, err := failover.Send(context.Background(), notification, nil); err != ErrNoHealthyHub || handledBy != nil {
+		t.Errorf(errfmt, "Send error", ErrNoHealthyHub, err)
+	}
+	if requests != 1 {
+		t.Errorf(errfmt, "requests", 1, requests)
+	}
+}
+
+// timeoutError is a transport timeout, such as the http.Client timeout
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "test timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func Test_FailoverHubTimeout(t *testing.T) {
+	var (
+		primary, primaryClient = initTestItems()
+		primaryRequests        int
+		secondaryRequests      int
+		secondary              = initFailoverTestItems(func() int { return http.StatusCreated }, &secondaryRequests)
+		notification, _        = NewNotification(Template, []byte("{}"))
+	)
+	primaryClient.execFunc = func(req *http.Request) ([]byte, *http.Response, error) {
+		primaryRequests++
+		return nil, nil, timeoutError{}
+	}
+
+	failover := NewFailoverHub([]*NotificationHub{primary, secondary}, nil)
+	_, _, handledBy, err := failover.Send(context.Background(), notification, nil)
+	if !errors.As(err, new(timeoutError)) || handledBy != primary {
+		t.Errorf(errfmt, "Send error", "primary timeout", err)
+	}
+	if secondaryRequests != 0 {
+		t.Errorf(errfmt, "secondary requests", 0, secondaryRequests)
+	}
+	if status := failover.Status(); status[0].ConsecutiveFailures != 1 {
+		t.Errorf(errfmt, "ConsecutiveFailures", 1, status[0].ConsecutiveFailures)
+	}
+
+	failover = NewFailoverHub([]*NotificationHub{primary, secondary}, &FailoverOptions{RetryTimeouts: true})
+	if _, _, handledBy, err = failover.Send(context.Background(), notification, nil); err != nil || handledBy != secondary {
+		t.Errorf(errfmt, "RetryTimeouts handledBy", "secondary", handledBy)
+	}
+	if secondaryRequests != 1 {
+		t.Errorf(errfmt, "secondary requests", 1, secondaryRequests)
+	}
+}
+
+func Test_FailoverHubCallerDeadline(t *testing.T) {
+	var (
+		primary, primaryClient = initTestItems()
+		secondaryRequests      int
+		secondary              = initFailoverTestItems(func() int { return http.StatusCreated }, &secondaryRequests)
+		failover               = NewFailoverHub([]*NotificationHub{primary, secondary}, &FailoverOptions{
+			FailureThreshold: 1,
+			RetryTimeouts:    true,
+		})
+		notification, _ = NewNotification(Template, []byte("{}"))
+	)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	primaryClient.execFunc = func(req *http.Request) ([]byte, *http.Response, error) {
+		<-req.Context().Done()
+		return nil, nil, req.Context().Err()
+	}
+
+	_, _, _, err := failover.Send(ctx, notification, nil)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf(errfmt, "Send error", context.DeadlineExceeded, err)
+	}
+	if secondaryRequests != 0 {
+		t.Errorf(errfmt, "secondary requests", 0, secondaryRequests)
+	}
+	if status := failover.Status(); !status[0].Healthy || status[0].ConsecutiveFailures != 0 {
+		t.Errorf(errfmt, "Status", "healthy primary", status[0])
+	}
+}
+
+func Test_FailoverHubSingleProbe(t *testing.T) {
+	var (
+		primaryStatus                      = http.StatusServiceUnavailable
+		primaryRequests, secondaryRequests int
+		primary                            = initFailoverTestItems(func() int { return primaryStatus }, &primaryRequests)
+		secondary                          = initFailoverTestItems(func() int { return http.StatusCreated }, &secondaryRequests)
+		notification, _                    = NewNotification(Template, []byte("{}"))
+		probes                             int32
+		probing                            = make(chan struct{})
+		release                            = make(chan struct{})
+		failover                           = NewFailoverHub([]*NotificationHub{primary, secondary}, &FailoverOptions{
+			FailureThreshold: 1,
+			Cooldown:         time.Millisecond,
+			Probe: func(ctx context.Context, h *NotificationHub) error {
+				if atomic.AddInt32(&probes, 1) == 1 {
+					close(probing)
+				}
+				<-release
+				return &utils.HTTPError{StatusCode: http.StatusServiceUnavailable}
+			},
+		})
+	)
+
+	if _, _, handledBy, _ := failover.Send(context.Background(), notification, nil); handledBy != secondary {
+		t.Fatalf(errfmt, "handledBy", "secondary", handledBy)
+	}
+	time.Sleep(5 * time.Millisecond)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		failover.Probe(context.Background())
+	}()
+	<-probing
+
+	// the hub being probed is skipped instead of probed again
+	for i := 0; i < 3; i++ {
+		if _, _, handledBy, err := failover.Send(context.Background(), notification, nil); err != nil || handledBy != secondary {
+			t.Errorf(errfmt, "handledBy during probe", "secondary", handledBy)
+		}
+	}
+	close(release)
+	<-done
+	if got := atomic.LoadInt32(&probes); got != 1 {
+		t.Errorf(errfmt, "probes", 1, got)
+	}
+	if primaryRequests != 1 {
+		t.Errorf(errfmt, "primary requests", 1, primaryRequests)
+	}
+}
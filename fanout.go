@@ -0,0 +1,169 @@
+package notificationhubs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/daresaydigital/azure-notificationhubs-go/tagexpr"
+)
+
+const defaultFanoutConcurrency = 4
+
+type (
+	// FanoutOptions configures how SendToTags splits and sends the tags
+	FanoutOptions struct {
+		// Filter is ANDed with every chunk of tags, or nil to send to the tags only
+		Filter tagexpr.Expr
+		// Concurrency is the maximum number of requests in flight, defaults to 4
+		Concurrency int
+	}
+
+	// FanoutResult is the aggregated result of a fanned out send
+	FanoutResult struct {
+		Chunks []FanoutChunk
+	}
+
+	// FanoutChunk is the result of sending to one tag expression
+	FanoutChunk struct {
+		Expression string
+		Tags       []string
+		Telemetry  *NotificationTelemetry
+		Err        error
+	}
+)
+
+// SendToTags publishes notification to every device having any of the tags
+// The tags are partitioned into expressions within the hub's tag expression limits,
+// each optionally ANDed with opts.Filter, and sent concurrently.
+// The result lists every chunk even if some of them failed, in which case an error is also returned
+func (h *NotificationHub) SendToTags(ctx context.Context, n *Notification, tags []string, opts *FanoutOptions) (result *FanoutResult, err error) {
+	result, err = h.sendToTags(ctx, n, tags, opts)
+	if err != nil {
+		return result, fmt.Errorf("notificationhubs.SendToTags: %w", err)
+	}
+	return
+}
+
+// MessageIDs returns the notification message IDs of the successful chunks
+func (r *FanoutResult) MessageIDs() []string {
+	var ids []string
+	for _, chunk := range r.Chunks {
+		if chunk.Err == nil && chunk.Telemetry != nil && chunk.Telemetry.NotificationMessageID != "" {
+			ids = append(ids, chunk.Telemetry.NotificationMessageID)
+		}
+	}
+	return ids
+}
+
+// Failed returns the chunks that could not be sent
+func (r *FanoutResult) Failed() []FanoutChunk {
+	var failed []FanoutChunk
+	for _, chunk := range r.Chunks {
+		if chunk.Err != nil {
+			failed = append(failed, chunk)
+		}
+	}
+	return failed
+}
+
+// Err returns an error describing the failed chunks, or nil if all chunks were sent
+func (r *FanoutResult) Err() error {
+	failed := r.Failed()
+	if len(failed) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%d of %d chunks failed, first error: %s", len(failed), len(r.Chunks), failed[0].Err)
+}
+
+// sendToTags partitions the tags into expressions and sends them concurrently
+func (h *NotificationHub) sendToTags(ctx context.Context, n *Notification, tags []string, opts *FanoutOptions) (*FanoutResult, error) {
+	if opts == nil {
+		opts = &FanoutOptions{}
+	}
+	chunks, err := partitionTags(tags, opts.Filter)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &FanoutResult{Chunks: chunks}
+	forEachParallel(len(chunks), opts.Concurrency, func(i int) {
+		chunk := &result.Chunks[i]
+		if chunk.Err = ctx.Err(); chunk.Err != nil {
+			return
+		}
+		_, chunk.Telemetry, chunk.Err = h.send(ctx, n, &chunk.Expression, nil)
+	})
+	return result, result.Err()
+}
+
+// sendToTagsTelemetry sends to the tags with the default options and returns the telemetry of the successful chunks
+func (h *NotificationHub) sendToTagsTelemetry(ctx context.Context, n *Notification, tags []string) (telemetry []*NotificationTelemetry, err error) {
+	result, err := h.sendToTags(ctx, n, tags, nil)
+	if result != nil {
+		for _, chunk := range result.Chunks {
+			if chunk.Err == nil {
+				telemetry = append(telemetry, chunk.Telemetry)
+			}
+		}
+	}
+	return
+}
+
+// partitionTags splits the tags into the fewest valid expressions
+func partitionTags(tags []string, filter tagexpr.Expr) ([]FanoutChunk, error) {
+	if len(tags) == 0 {
+		return nil, errors.New("no tags to send to")
+	}
+
+	size := tagexpr.MaxOrTags
+	if filter != nil {
+		if err := tagexpr.Validate(filter); err != nil {
+			return nil, err
+		}
+		if size = tagexpr.MaxTags - len(tagexpr.TagNames(filter)); size < 1 {
+			return nil, fmt.Errorf("filter %q leaves no room for tags", filter)
+		}
+	}
+
+	var chunks []FanoutChunk
+	for start := 0; start < len(tags); start += size {
+		end := start + size
+		if end > len(tags) {
+			end = len(tags)
+		}
+		expr := tagexpr.Or(tagexpr.Tags(tags[start:end]...)...)
+		if filter != nil {
+			expr = tagexpr.And(expr, filter)
+		}
+		if err := tagexpr.Validate(expr); err != nil {
+			return nil, err
+		}
+		chunks = append(chunks, FanoutChunk{Expression: expr.String(), Tags: tags[start:end]})
+	}
+	return chunks, nil
+}
+
+// forEachParallel calls fn for every index below n with at most concurrency calls in flight
+func forEachParallel(n, concurrency int, fn func(i int)) {
+	if concurrency < 1 {
+		concurrency = defaultFanoutConcurrency
+	}
+	var (
+		wg  sync.WaitGroup
+		sem = make(chan struct{}, concurrency)
+	)
+	for i := 0; i < n; i++ {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(i int) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			fn(i)
+		}(i)
+	}
+	wg.Wait()
+}
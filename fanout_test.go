@@ -0,0 +1,119 @@
+package notificationhubs_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/daresaydigital/azure-notificationhubs-go"
+	"github.com/daresaydigital/azure-notificationhubs-go/tagexpr"
+)
+
+func Test_SendToTags(t *testing.T) {
+	var (
+		nhub, notification, mockClient = initNotificationTestItems()
+		tags                           = []string{"tag0", "tag1", "tag2", "tag3", "tag4", "tag5", "tag6", "tag7", "tag8", "tag9"}
+		expectedError                  = errors.New("test error")
+		mu                             sync.Mutex
+		inFlight, maxInFlight          int
+	)
+
+	mockClient.execFunc = func(req *http.Request) ([]byte, *http.Response, error) {
+		mu.Lock()
+		inFlight++
+		if inFlight > maxInFlight {
+			maxInFlight = inFlight
+		}
+		mu.Unlock()
+		time.Sleep(10 * time.Millisecond)
+		mu.Lock()
+		inFlight--
+		mu.Unlock()
+
+		expression := req.Header.Get("ServiceBusNotification-Tags")
+		if strings.Contains(expression, "tag8") {
+			return nil, nil, expectedError
+		}
+		id := strings.TrimPrefix(strings.Split(expression, " ")[0], "(")
+		mockResponse := http.Response{
+			Header: http.Header{
+				"Location": []string{
+					fmt.Sprintf("https://messages.servicebus.windows.net/messagebus/messages/%s?api-version=2016-10", id),
+				},
+			},
+		}
+		return nil, &mockResponse, nil
+	}
+
+	result, err := nhub.SendToTags(context.Background(), notification, tags, &FanoutOptions{
+		Filter:      tagexpr.MustParse("ios && !beta"),
+		Concurrency: 2,
+	})
+	if err == nil || !strings.Contains(err.Error(), expectedError.Error()) {
+		t.Errorf(errfmt, "SendToTags error", expectedError, err)
+	}
+	if result == nil {
+		t.Fatalf(errfmt, "result", "not nil", result)
+	}
+
+	expectedExpressions := []string{
+		"(tag0 || tag1 || tag2 || tag3) && ios && !beta",
+		"(tag4 || tag5 || tag6 || tag7) && ios && !beta",
+		"(tag8 || tag9) && ios && !beta",
+	}
+	var gotExpressions []string
+	for _, chunk := range result.Chunks {
+		gotExpressions = append(gotExpressions, chunk.Expression)
+	}
+	if !reflect.DeepEqual(gotExpressions, expectedExpressions) {
+		t.Errorf(errfmt, "expressions", expectedExpressions, gotExpressions)
+	}
+
+	ids := result.MessageIDs()
+	sort.Strings(ids)
+	if expected := []string{"tag0", "tag4"}; !reflect.DeepEqual(ids, expected) {
+		t.Errorf(errfmt, "message IDs", expected, ids)
+	}
+	if failed := result.Failed(); len(failed) != 1 || failed[0].Err != expectedError {
+		t.Errorf(errfmt, "failed chunks", 1, failed)
+	}
+	if maxInFlight > 2 {
+		t.Errorf(errfmt, "requests in flight", 2, maxInFlight)
+	}
+}
+
+func Test_SendToTagsInvalid(t *testing.T) {
+	var (
+		nhub, notification, mockClient = initNotificationTestItems()
+		testCases                      = []struct {
+			tags   []string
+			filter tagexpr.Expr
+		}{
+			{tags: nil},
+			{tags: []string{"invalid tag"}},
+			{tags: []string{"tag"}, filter: tagexpr.And(tagexpr.Tags("a", "b", "c", "d", "e", "f")...)},
+		}
+	)
+
+	mockClient.execFunc = func(req *http.Request) ([]byte, *http.Response, error) {
+		t.Errorf("unexpected request to %s", req.URL)
+		return nil, nil, nil
+	}
+
+	for i, testCase := range testCases {
+		result, err := nhub.SendToTags(context.Background(), notification, testCase.tags, &FanoutOptions{Filter: testCase.filter})
+		if err == nil {
+			t.Errorf(errfmt, fmt.Sprintf("case %d error", i), "error", err)
+		}
+		if result != nil {
+			t.Errorf(errfmt, fmt.Sprintf("case %d result", i), nil, result)
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package notificationhubs
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+)
+
+type (
+	// FeedbackBlob is a blob in the PNS feedback container
+	FeedbackBlob struct {
+		Name         string
+		LastModified time.Time
+		URL          *url.URL
+	}
+
+	// FeedbackRecord is the feedback of a push notification service about one device handle
+	FeedbackRecord struct {
+		Platform       InstallationPlatform    `json:"platform"`
+		Handle         string                  `json:"pnsHandle"`
+		Outcome        NotificationOutcomeName `json:"outcome"`
+		RegistrationID string                  `json:"registrationId,omitempty"`
+		InstallationID string                  `json:"installationId,omitempty"`
+		Timestamp      *time.Time              `json:"timestamp,omitempty"`
+	}
+
+	// PruneResult lists what PruneExpiredHandles did for every expired handle
+	PruneResult struct {
+		Deleted []string
+		Skipped []string
+		Failed  map[string]error
+	}
+
+	// blobList is the response of the blob service List Blobs operation
+	blobList struct {
+		Blobs []struct {
+			Name       string `xml:"Name"`
+			Properties struct {
+				LastModified string `xml:"Last-Modified"`
+			} `xml:"Properties"`
+		} `xml:"Blobs>Blob"`
+		NextMarker string `xml:"NextMarker"`
+	}
+)
+
+// FeedbackContainerURL reads the shared access signature URL of the hub's PNS feedback container
+func (h *NotificationHub) FeedbackContainerURL(ctx context.Context) (*url.URL, error) {
+	raw, _, err := h.exec(ctx, "FeedbackContainerURL", getMethod, h.generateAPIURL("feedbackcontainer"), Headers{}, nil)
+	if err != nil {
+		return nil, err
+	}
+	return url.Parse(strings.TrimSpace(string(raw)))
+}
+
+// FeedbackBlobs lists the blobs in the feedback container modified at or after since
+func (h *NotificationHub) FeedbackBlobs(ctx context.Context, containerURL *url.URL, since time.Time) ([]FeedbackBlob, error) {
+	var (
+		blobs  []FeedbackBlob
+		marker = ""
+	)
+	for {
+		listURL := *containerURL
+		query := listURL.Query()
+		query.Set("restype", "container")
+		query.Set("comp", "list")
+		if marker != "" {
+			query.Set("marker", marker)
+		}
+		listURL.RawQuery = query.Encode()
+
+		raw, err := h.execBlob(ctx, "FeedbackBlobs", &listURL)
+		if err != nil {
+			return nil, err
+		}
+		var list blobList
+		if err = xml.Unmarshal(raw, &list); err != nil {
+			return nil, err
+		}
+		for _, blob := range list.Blobs {
+			lastModified, err := time.Parse(time.RFC1123, blob.Properties.LastModified)
+			if err != nil {
+				return nil, err
+			}
+			if lastModified.Before(since) {
+				continue
+			}
+			blobURL := *containerURL
+			blobURL.Path = path.Join(containerURL.Path, blob.Name)
+			blobs = append(blobs, FeedbackBlob{Name: blob.Name, LastModified: lastModified, URL: &blobURL})
+		}
+		if marker = list.NextMarker; marker == "" {
+			return blobs, nil
+		}
+	}
+}
+
+// FeedbackRecords downloads a feedback blob and calls fn for every record in it
+// Iteration stops at the first error returned by fn. The blob is read in full,
+// so it must fit in the MaxResponseBodySize of the HTTP client, 64 MiB by default
+func (h *NotificationHub) FeedbackRecords(ctx context.Context, blob FeedbackBlob, fn func(FeedbackRecord) error) error {
+	raw, err := h.execBlob(ctx, "FeedbackRecords", blob.URL)
+	if err != nil {
+		return err
+	}
+	return forEachJSONLine(raw, func(line []byte) error {
+		var record FeedbackRecord
+		if err := json.Unmarshal(line, &record); err != nil {
+			return err
+		}
+		return fn(record)
+	})
+}
+
+// Feedback calls fn for every feedback record in blobs modified at or after since
+func (h *NotificationHub) Feedback(ctx context.Context, since time.Time, fn func(FeedbackRecord) error) error {
+	containerURL, err := h.FeedbackContainerURL(ctx)
+	if err != nil {
+		return err
+	}
+	blobs, err := h.FeedbackBlobs(ctx, containerURL, since)
+	if err != nil {
+		return err
+	}
+	for _, blob := range blobs {
+		if err = h.FeedbackRecords(ctx, blob, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PruneExpiredHandles deletes the installation or registration of every handle
+// reported as expired in feedback modified at or after since.
+// Handles reported without an installation or registration ID are skipped
+func (h *NotificationHub) PruneExpiredHandles(ctx context.Context, since time.Time) (*PruneResult, error) {
+	result := &PruneResult{Failed: map[string]error{}}
+	err := h.Feedback(ctx, since, func(record FeedbackRecord) error {
+		if record.Outcome != ExpiredChannel {
+			return nil
+		}
+		var err error
+		switch {
+		case record.InstallationID != "":
+			err = h.Uninstall(ctx, record.InstallationID)
+		case record.RegistrationID != "":
+			err = h.Unregister(ctx, RegisteredDevice{RegistrationID: record.RegistrationID, ETag: "*"})
+		default:
+			result.Skipped = append(result.Skipped, record.Handle)
+			return nil
+		}
+		if err != nil {
+			result.Failed[record.Handle] = err
+		} else {
+			result.Deleted = append(result.Deleted, record.Handle)
+		}
+		return ctx.Err()
+	})
+	if err != nil {
+		return result, err
+	}
+	if len(result.Failed) > 0 {
+		return result, errors.New("could not delete all expired handles")
+	}
+	return result, nil
+}
@@ -0,0 +1,134 @@
+package notificationhubs_test
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/daresaydigital/azure-notificationhubs-go"
+	"github.com/daresaydigital/azure-notificationhubs-go/utils"
+)
+
+// newFeedbackServer serves both the hub API and a blob container with feedback
+func newFeedbackServer(t *testing.T, deleted *[]string) *httptest.Server {
+	var (
+		mu     sync.Mutex
+		server *httptest.Server
+	)
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var fixture string
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/testhub/feedbackcontainer":
+			if r.Header.Get("Authorization") == "" {
+				t.Errorf(errfmt, "Authorization header", "SharedAccessSignature", "")
+			}
+			_, _ = w.Write([]byte(server.URL + "/feedback?sv=2015-07-08&sr=c&sig=signature&sp=rl"))
+			return
+		case r.Method == http.MethodGet && r.URL.Path == "/feedback":
+			if r.URL.Query().Get("comp") != "list" || r.URL.Query().Get("sig") != "signature" {
+				t.Errorf(errfmt, "list query", "comp=list&sig=signature", r.URL.RawQuery)
+			}
+			fixture = "./fixtures/feedbackBlobs.xml"
+		case r.Method == http.MethodGet && r.URL.Path == "/feedback/2020/04/20/09/feedback.json":
+			fixture = "./fixtures/feedback.json"
+		case r.Method == http.MethodDelete:
+			mu.Lock()
+			*deleted = append(*deleted, r.URL.Path+" "+r.Header.Get("If-Match"))
+			mu.Unlock()
+			return
+		default:
+			http.NotFound(w, r)
+			return
+		}
+		data, _ := ioutil.ReadFile(fixture)
+		_, _ = w.Write(data)
+	}))
+	return server
+}
+
+func initFeedbackTestItems(t *testing.T, deleted *[]string) (*NotificationHub, *httptest.Server) {
+	var (
+		server       = newFeedbackServer(t, deleted)
+		serverURL, _ = url.Parse(server.URL)
+		nhub         = NewNotificationHub(connectionString, hubPath)
+	)
+	nhub.HubURL.Scheme = serverURL.Scheme
+	nhub.HubURL.Host = serverURL.Host
+	return nhub, server
+}
+
+func Test_Feedback(t *testing.T) {
+	nhub, server := initFeedbackTestItems(t, nil)
+	defer server.Close()
+
+	containerURL, err := nhub.FeedbackContainerURL(context.Background())
+	if err != nil {
+		t.Fatalf(errfmt, "error", nil, err)
+	}
+	if containerURL.Path != "/feedback" {
+		t.Errorf(errfmt, "container path", "/feedback", containerURL.Path)
+	}
+
+	since := time.Date(2020, 4, 20, 0, 0, 0, 0, time.UTC)
+	blobs, err := nhub.FeedbackBlobs(context.Background(), containerURL, since)
+	if err != nil {
+		t.Fatalf(errfmt, "error", nil, err)
+	}
+	if len(blobs) != 1 || blobs[0].Name != "2020/04/20/09/feedback.json" {
+		t.Fatalf(errfmt, "blobs", "2020/04/20/09/feedback.json", blobs)
+	}
+	if blobs[0].URL.Query().Get("sig") != "signature" {
+		t.Errorf(errfmt, "blob signature", "signature", blobs[0].URL.RawQuery)
+	}
+
+	var handles []string
+	err = nhub.Feedback(context.Background(), since, func(record FeedbackRecord) error {
+		handles = append(handles, record.Handle)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf(errfmt, "error", nil, err)
+	}
+	if expected := []string{"ABCDEF", "ANDROIDID", "UNKNOWNID", "QWERTY"}; !reflect.DeepEqual(handles, expected) {
+		t.Errorf(errfmt, "handles", expected, handles)
+	}
+
+	nhub.SetHTTPClient(utils.NewHubHTTPClientWithOptions(&utils.HubHTTPClientOptions{MaxResponseBodySize: 16}))
+	err = nhub.FeedbackRecords(context.Background(), blobs[0], func(FeedbackRecord) error { return nil })
+	var sizeErr *utils.ResponseTooLargeError
+	if !errors.As(err, &sizeErr) || !strings.Contains(err.Error(), "FeedbackRecords: blob is larger than the 16 bytes response limit") {
+		t.Errorf(errfmt, "FeedbackRecords error", "response limit error", err)
+	}
+}
+
+func Test_PruneExpiredHandles(t *testing.T) {
+	var deleted []string
+	nhub, server := initFeedbackTestItems(t, &deleted)
+	defer server.Close()
+
+	result, err := nhub.PruneExpiredHandles(context.Background(), time.Date(2020, 4, 20, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf(errfmt, "error", nil, err)
+	}
+	if expected := []string{"ABCDEF", "ANDROIDID"}; !reflect.DeepEqual(result.Deleted, expected) {
+		t.Errorf(errfmt, "deleted handles", expected, result.Deleted)
+	}
+	if expected := []string{"UNKNOWNID"}; !reflect.DeepEqual(result.Skipped, expected) {
+		t.Errorf(errfmt, "skipped handles", expected, result.Skipped)
+	}
+	expectedRequests := []string{
+		"/testhub/installations/0a92196c-20c3-4308-8046-c384c902d0ff ",
+		"/testhub/registrations/4603854756731398046-26535929789529194-1 *",
+	}
+	if !reflect.DeepEqual(deleted, expectedRequests) {
+		t.Errorf(errfmt, "delete requests", expectedRequests, deleted)
+	}
+}
@@ -1,3 +1,3 @@
 module github.com/daresaydigital/azure-notificationhubs-go
 
-go 1.12
+go 1.13
@@ -14,7 +14,7 @@ func (h *NotificationHub) Installation(ctx context.Context, installationID strin
 		instURL = h.generateAPIURL(path.Join("installations", installationID))
 	)
 
-	raw, _, err = h.exec(ctx, getMethod, instURL, Headers{}, nil)
+	raw, _, err = h.exec(ctx, "Installation", getMethod, instURL, Headers{}, nil)
 	if err != nil {
 		return
 	}
@@ -37,7 +37,7 @@ func (h *NotificationHub) Install(ctx context.Context, installation Installation
 		return
 	}
 
-	_, _, err = h.exec(ctx, putMethod, instURL, headers, bytes.NewBuffer(raw))
+	_, _, err = h.exec(ctx, "Install", putMethod, instURL, headers, bytes.NewBuffer(raw))
 	return
 }
 
@@ -55,7 +55,7 @@ func (h *NotificationHub) Update(ctx context.Context, installationID string, cha
 		return
 	}
 
-	_, _, err = h.exec(ctx, patchMethod, instURL, headers, bytes.NewBuffer(raw))
+	_, _, err = h.exec(ctx, "Update", patchMethod, instURL, headers, bytes.NewBuffer(raw))
 	return
 }
 
@@ -64,6 +64,11 @@ func SetPushChannel(pushChannel string) InstallationChange {
 	return InstallationChange{Op: InstallationChangeReplace, Path: "/pushChannel", Value: pushChannel}
 }
 
+// SetUserID sets the installation user ID
+func SetUserID(userID string) InstallationChange {
+	return InstallationChange{Op: InstallationChangeReplace, Path: "/userId", Value: userID}
+}
+
 // SetTags sets the installation tags
 func SetTags(tags ...string) InstallationChange {
 	raw, _ := json.Marshal(tags)
@@ -209,6 +214,6 @@ func (h *NotificationHub) Uninstall(ctx context.Context, installationID string)
 		}
 	)
 
-	_, _, err = h.exec(ctx, deleteMethod, instURL, headers, nil)
+	_, _, err = h.exec(ctx, "Uninstall", deleteMethod, instURL, headers, nil)
 	return
 }
@@ -113,6 +113,7 @@ func Test_Update(t *testing.T) {
 
 	err := nhub.Update(context.Background(), installationID,
 		SetPushChannel("pushChannel"),
+		SetUserID("userID"),
 		SetTags("tag1", "tag2"),
 		AddTag("tag"),
 		RemoveTag("tag"),
@@ -7,6 +7,17 @@ const (
 	telemetryAPIVersionValue = "2016-07"
 	directParam              = "direct"
 
+	// the hub accepts at most this many handles per batch
+	maxDirectBatchSize = 1000
+
+	// for paging through lists
+	continuationTokenParam  = "ContinuationToken"
+	continuationTokenHeader = "X-MS-ContinuationToken"
+
+	// reserved tags added by the hub to every installation
+	userIDTagPrefix         = "$UserId:"
+	installationIDTagPrefix = "$InstallationId:"
+
 	// for connection string parsing
 	schemeServiceBus  = "sb"
 	schemeDefault     = "https"
@@ -70,4 +81,20 @@ const (
     </GcmTemplateRegistrationDescription>
   </content>
 </entry>`
+
+	// jobXMLString is the XML string for submitting an import or export job
+	// Replace {{Type}}, {{OutputContainerUri}} and {{ImportFileUri}} with the escaped values
+	jobXMLString string = `<?xml version="1.0" encoding="utf-8"?>
+<entry xmlns="http://www.w3.org/2005/Atom">
+  <content type="application/xml">
+    <NotificationHubJob xmlns:i="http://www.w3.org/2001/XMLSchema-instance" xmlns="http://schemas.microsoft.com/netservices/2010/10/servicebus/connect">
+      <Type>{{Type}}</Type>
+      <OutputContainerUri>{{OutputContainerUri}}</OutputContainerUri>
+      <ImportFileUri>{{ImportFileUri}}</ImportFileUri>
+    </NotificationHubJob>
+  </content>
+</entry>`
+
+	// registrationDescriptionNamespace is the XML namespace of registration descriptions
+	registrationDescriptionNamespace = "http://schemas.microsoft.com/netservices/2010/10/servicebus/connect"
 )
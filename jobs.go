@@ -0,0 +1,259 @@
+package notificationhubs
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"time"
+)
+
+const defaultJobPollInterval = 5 * time.Second
+
+type (
+	// NotificationHubJob is an import or export job
+	NotificationHubJob struct {
+		ID                 string                       `xml:"JobId"`
+		Type               NotificationHubJobType       `xml:"Type"`
+		Status             NotificationHubJobStatus     `xml:"Status"`
+		Progress           float64                      `xml:"Progress"`
+		OutputContainerURI string                       `xml:"OutputContainerUri"`
+		ImportFileURI      string                       `xml:"ImportFileUri"`
+		Failure            string                       `xml:"Failure"`
+		OutputFilePath     string                       `xml:"-"`
+		FailedFilePath     string                       `xml:"-"`
+		CreatedAt          *time.Time                   `xml:"CreatedAt"`
+		UpdatedAt          *time.Time                   `xml:"UpdatedAt"`
+		OutputProperties   []NotificationHubJobProperty `xml:"OutputProperties>KeyValueOfstringstring"`
+	}
+
+	// NotificationHubJobProperty is a key value pair describing the output of a job
+	NotificationHubJobProperty struct {
+		Key   string `xml:"Key"`
+		Value string `xml:"Value"`
+	}
+
+	// ImportFileWriter writes registrations in the format read by import jobs
+	ImportFileWriter struct {
+		w io.Writer
+	}
+
+	// jobEntry is the Atom entry wrapping a job
+	jobEntry struct {
+		Job *NotificationHubJob `xml:"content>NotificationHubJob"`
+	}
+
+	// jobFeed is the Atom feed wrapping a list of jobs
+	jobFeed struct {
+		Entries []jobEntry `xml:"entry"`
+	}
+
+	// registrationDescription is a registration line in an import file
+	registrationDescription struct {
+		XMLName           xml.Name
+		RegistrationID    string `xml:"RegistrationId,omitempty"`
+		Tags              string `xml:"Tags,omitempty"`
+		DeviceToken       string `xml:"DeviceToken,omitempty"`
+		GcmRegistrationID string `xml:"GcmRegistrationId,omitempty"`
+		BodyTemplate      string `xml:"BodyTemplate,omitempty"`
+	}
+)
+
+// SubmitJob submits an import or export job to the Azure hub
+// Set OutputContainerURI to a blob container shared access signature URL with write access,
+// and ImportFileURI to a blob shared access signature URL with read access for import jobs
+func (h *NotificationHub) SubmitJob(ctx context.Context, job NotificationHubJob) (raw []byte, result *NotificationHubJob, err error) {
+	var (
+		headers = map[string]string{
+			"Content-Type": "application/atom+xml;type=entry;charset=utf-8",
+		}
+		payload = strings.NewReplacer(
+			"{{Type}}", xmlEscape(string(job.Type)),
+			"{{OutputContainerUri}}", xmlEscape(job.OutputContainerURI),
+			"{{ImportFileUri}}", xmlEscape(job.ImportFileURI),
+		).Replace(jobXMLString)
+	)
+
+	raw, _, err = h.exec(ctx, "SubmitJob", postMethod, h.generateAPIURL("jobs"), headers, bytes.NewBufferString(payload))
+	if err != nil {
+		return
+	}
+	result, err = parseJob(raw)
+	return
+}
+
+// ExportRegistrations submits a job exporting all registrations to the output container
+func (h *NotificationHub) ExportRegistrations(ctx context.Context, outputContainerURI string) (*NotificationHubJob, error) {
+	_, job, err := h.SubmitJob(ctx, NotificationHubJob{Type: ExportRegistrationsJob, OutputContainerURI: outputContainerURI})
+	return job, err
+}
+
+// ImportRegistrations submits a job of one of the import types reading the registrations in the import file
+func (h *NotificationHub) ImportRegistrations(ctx context.Context, jobType NotificationHubJobType, importFileURI, outputContainerURI string) (*NotificationHubJob, error) {
+	_, job, err := h.SubmitJob(ctx, NotificationHubJob{Type: jobType, ImportFileURI: importFileURI, OutputContainerURI: outputContainerURI})
+	return job, err
+}
+
+// GetJob reads one specific job
+func (h *NotificationHub) GetJob(ctx context.Context, jobID string) (raw []byte, job *NotificationHubJob, err error) {
+	raw, _, err = h.exec(ctx, "GetJob", getMethod, h.generateAPIURL(path.Join("jobs", jobID)), Headers{}, nil)
+	if err != nil {
+		return
+	}
+	job, err = parseJob(raw)
+	return
+}
+
+// ListJobs reads all jobs of the hub
+func (h *NotificationHub) ListJobs(ctx context.Context) (raw []byte, jobs []*NotificationHubJob, err error) {
+	raw, _, err = h.exec(ctx, "ListJobs", getMethod, h.generateAPIURL("jobs"), Headers{}, nil)
+	if err != nil {
+		return
+	}
+	var feed jobFeed
+	if err = xml.Unmarshal(raw, &feed); err != nil {
+		return
+	}
+	for _, entry := range feed.Entries {
+		if entry.Job != nil {
+			entry.Job.normalize()
+			jobs = append(jobs, entry.Job)
+		}
+	}
+	return
+}
+
+// WaitForJob polls the job every interval until it has completed or failed
+// An interval of zero polls every 5 seconds
+func (h *NotificationHub) WaitForJob(ctx context.Context, jobID string, interval time.Duration) (*NotificationHubJob, error) {
+	if interval <= 0 {
+		interval = defaultJobPollInterval
+	}
+	for {
+		_, job, err := h.GetJob(ctx, jobID)
+		if err != nil {
+			return nil, err
+		}
+		switch job.Status {
+		case JobCompleted:
+			return job, nil
+		case JobFailed:
+			return job, fmt.Errorf("job %s failed: %s", job.ID, job.Failure)
+		}
+
+		timer := time.NewTimer(interval)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return job, ctx.Err()
+		}
+	}
+}
+
+// parseJob reads a job from an Atom entry
+func parseJob(raw []byte) (*NotificationHubJob, error) {
+	var entry jobEntry
+	if err := xml.Unmarshal(raw, &entry); err != nil {
+		return nil, err
+	}
+	if entry.Job == nil {
+		return nil, errors.New("response does not contain a job")
+	}
+	entry.Job.normalize()
+	return entry.Job, nil
+}
+
+// normalize reads the output file paths from the output properties
+func (j *NotificationHubJob) normalize() {
+	for _, property := range j.OutputProperties {
+		switch property.Key {
+		case "OutputFilePath":
+			j.OutputFilePath = property.Value
+		case "FailedFilePath":
+			j.FailedFilePath = property.Value
+		}
+	}
+}
+
+// NewImportFileWriter initializes and returns ImportFileWriter pointer
+func NewImportFileWriter(w io.Writer) *ImportFileWriter {
+	return &ImportFileWriter{w: w}
+}
+
+// WriteRegistration writes a native registration, set the RegistrationID for update and delete imports
+func (w *ImportFileWriter) WriteRegistration(r Registration) error {
+	description := registrationDescription{RegistrationID: r.RegistrationID, Tags: r.Tags}
+	switch r.NotificationFormat {
+	case AppleFormat:
+		description.XMLName.Local = "AppleRegistrationDescription"
+		description.DeviceToken = r.DeviceID
+	case GcmFormat:
+		description.XMLName.Local = "GcmRegistrationDescription"
+		description.GcmRegistrationID = r.DeviceID
+	default:
+		return errors.New("Notification format not implemented")
+	}
+	return w.write(description)
+}
+
+// WriteTemplateRegistration writes a template registration, set the RegistrationID for update and delete imports
+func (w *ImportFileWriter) WriteTemplateRegistration(r TemplateRegistration) error {
+	description := registrationDescription{RegistrationID: r.RegistrationID, Tags: r.Tags, BodyTemplate: r.Template}
+	switch r.Platform {
+	case ApplePlatform:
+		description.XMLName.Local = "AppleTemplateRegistrationDescription"
+		description.DeviceToken = r.DeviceID
+	case GcmPlatform:
+		description.XMLName.Local = "GcmTemplateRegistrationDescription"
+		description.GcmRegistrationID = r.DeviceID
+	default:
+		return errors.New("Notification format not implemented")
+	}
+	return w.write(description)
+}
+
+// write writes the description on one line
+func (w *ImportFileWriter) write(description registrationDescription) error {
+	description.XMLName.Space = registrationDescriptionNamespace
+	raw, err := xml.Marshal(description)
+	if err != nil {
+		return err
+	}
+	_, err = w.w.Write(append(raw, '\n'))
+	return err
+}
+
+// ReadExportFile reads the registrations of an export job output file and calls fn for every registration
+// Iteration stops at the first error returned by fn
+func ReadExportFile(r io.Reader, fn func(RegistrationContent) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var content RegistrationContent
+		if err := xml.Unmarshal([]byte("<content>"+string(line)+"</content>"), &content); err != nil {
+			return err
+		}
+		content.normalize()
+		if err := fn(content); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// xmlEscape escapes s for use as XML text
+func xmlEscape(s string) string {
+	var buf bytes.Buffer
+	_ = xml.EscapeText(&buf, []byte(s))
+	return buf.String()
+}
@@ -0,0 +1,173 @@
+package notificationhubs_test
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/daresaydigital/azure-notificationhubs-go"
+)
+
+const jobsURL = "https://testhub-ns.servicebus.windows.net/testhub/jobs?api-version=2015-01"
+
+func Test_SubmitJob(t *testing.T) {
+	var (
+		nhub, mockClient = initTestItems()
+		containerURI     = "https://testhubblob.blob.core.windows.net/export?sv=2015-07-08&sr=c&sig=signature&sp=rwl"
+	)
+
+	mockClient.execFunc = func(req *http.Request) ([]byte, *http.Response, error) {
+		if gotMethod := req.Method; gotMethod != postMethod {
+			t.Errorf(errfmt, "method", postMethod, gotMethod)
+		}
+		if gotURL := req.URL.String(); gotURL != jobsURL {
+			t.Errorf(errfmt, "URL", jobsURL, gotURL)
+		}
+		body, _ := ioutil.ReadAll(req.Body)
+		for _, expected := range []string{
+			"<Type>ExportRegistrations</Type>",
+			"<OutputContainerUri>https://testhubblob.blob.core.windows.net/export?sv=2015-07-08&amp;sr=c&amp;sig=signature&amp;sp=rwl</OutputContainerUri>",
+			"<ImportFileUri></ImportFileUri>",
+		} {
+			if !strings.Contains(string(body), expected) {
+				t.Errorf(errfmt, "body containing", expected, string(body))
+			}
+		}
+		data, err := ioutil.ReadFile("./fixtures/jobResult.xml")
+		return data, nil, err
+	}
+
+	job, err := nhub.ExportRegistrations(context.Background(), containerURI)
+	if err != nil {
+		t.Fatalf(errfmt, "error", nil, err)
+	}
+	createdAt := time.Date(2020, 4, 20, 9, 10, 11, 123456700, time.UTC)
+	if job.ID != "1" || job.Status != JobCompleted || job.Progress != 100 || job.OutputContainerURI != containerURI {
+		t.Errorf(errfmt, "job", "completed export job 1", job)
+	}
+	if job.OutputFilePath != "export/1/Output.txt" || job.FailedFilePath != "export/1/Failed.txt" {
+		t.Errorf(errfmt, "output paths", "export/1/Output.txt, export/1/Failed.txt", job.OutputFilePath+", "+job.FailedFilePath)
+	}
+	if job.CreatedAt == nil || !job.CreatedAt.Equal(createdAt) {
+		t.Errorf(errfmt, "created at", createdAt, job.CreatedAt)
+	}
+}
+
+func Test_ListJobs(t *testing.T) {
+	nhub, mockClient := initTestItems()
+
+	mockClient.execFunc = func(req *http.Request) ([]byte, *http.Response, error) {
+		if gotURL := req.URL.String(); gotURL != jobsURL {
+			t.Errorf(errfmt, "URL", jobsURL, gotURL)
+		}
+		data, err := ioutil.ReadFile("./fixtures/jobsResult.xml")
+		return data, nil, err
+	}
+
+	_, jobs, err := nhub.ListJobs(context.Background())
+	if err != nil {
+		t.Fatalf(errfmt, "error", nil, err)
+	}
+	if len(jobs) != 2 || jobs[1].Type != ImportCreateRegistrationsJob || jobs[1].Failure != "Import file not found" {
+		t.Errorf(errfmt, "jobs", "export and failed import", jobs)
+	}
+}
+
+func Test_WaitForJob(t *testing.T) {
+	var (
+		nhub, mockClient = initTestItems()
+		requests         = 0
+	)
+
+	mockClient.execFunc = func(req *http.Request) ([]byte, *http.Response, error) {
+		requests++
+		u, _ := url.Parse(jobsURL)
+		u.Path += "/1"
+		if gotURL := req.URL.String(); gotURL != u.String() {
+			t.Errorf(errfmt, "URL", u.String(), gotURL)
+		}
+		if requests < 3 {
+			return []byte(`<entry><content><NotificationHubJob><JobId>1</JobId><Status>Running</Status></NotificationHubJob></content></entry>`), nil, nil
+		}
+		data, err := ioutil.ReadFile("./fixtures/jobResult.xml")
+		return data, nil, err
+	}
+
+	job, err := nhub.WaitForJob(context.Background(), "1", time.Millisecond)
+	if err != nil {
+		t.Fatalf(errfmt, "error", nil, err)
+	}
+	if job.Status != JobCompleted || requests != 3 {
+		t.Errorf(errfmt, "job status after 3 requests", JobCompleted, job.Status)
+	}
+
+	mockClient.execFunc = func(req *http.Request) ([]byte, *http.Response, error) {
+		return []byte(`<entry><content><NotificationHubJob><JobId>2</JobId><Status>Failed</Status><Failure>Import file not found</Failure></NotificationHubJob></content></entry>`), nil, nil
+	}
+	if _, err = nhub.WaitForJob(context.Background(), "2", time.Millisecond); err == nil || !strings.Contains(err.Error(), "Import file not found") {
+		t.Errorf(errfmt, "error", "Import file not found", err)
+	}
+}
+
+func Test_ImportExportFiles(t *testing.T) {
+	var buf bytes.Buffer
+	writer := NewImportFileWriter(&buf)
+	if err := writer.WriteRegistration(Registration{DeviceID: "ABCDEF", NotificationFormat: AppleFormat, Tags: "tag1,tag2"}); err != nil {
+		t.Fatalf(errfmt, "error", nil, err)
+	}
+	if err := writer.WriteTemplateRegistration(TemplateRegistration{DeviceID: "ANDROIDID", Platform: GcmPlatform, Template: `{"data":{"message":"$(message)"}}`, RegistrationID: "1"}); err != nil {
+		t.Fatalf(errfmt, "error", nil, err)
+	}
+	if err := writer.WriteRegistration(Registration{DeviceID: "X", NotificationFormat: WindowsFormat}); err == nil {
+		t.Errorf(errfmt, "error", "Notification format not implemented", err)
+	}
+
+	expected := `<AppleRegistrationDescription xmlns="http://schemas.microsoft.com/netservices/2010/10/servicebus/connect"><Tags>tag1,tag2</Tags><DeviceToken>ABCDEF</DeviceToken></AppleRegistrationDescription>
+<GcmTemplateRegistrationDescription xmlns="http://schemas.microsoft.com/netservices/2010/10/servicebus/connect"><RegistrationId>1</RegistrationId><GcmRegistrationId>ANDROIDID</GcmRegistrationId><BodyTemplate>{&#34;data&#34;:{&#34;message&#34;:&#34;$(message)&#34;}}</BodyTemplate></GcmTemplateRegistrationDescription>
+`
+	if buf.String() != expected {
+		t.Errorf(errfmt, "import file", expected, buf.String())
+	}
+
+	var read []RegistrationContent
+	collect := func(content RegistrationContent) error {
+		read = append(read, content)
+		return nil
+	}
+	if err := ReadExportFile(&buf, collect); err != nil {
+		t.Fatalf(errfmt, "error", nil, err)
+	}
+	if len(read) != 2 || read[1].Target != GcmTemplatePlatform || read[1].RegisteredDevice.Template != `{"data":{"message":"$(message)"}}` {
+		t.Errorf(errfmt, "round trip", "apple and gcm template registrations", read)
+	}
+
+	file, err := os.Open("./fixtures/exportRegistrations.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	read = nil
+	if err = ReadExportFile(file, collect); err != nil {
+		t.Fatalf(errfmt, "error", nil, err)
+	}
+	expectedDevice := &RegisteredDevice{
+		DeviceID:       "ABCDEF",
+		ETag:           "1",
+		ExpirationTime: &endOfEpoch,
+		RegistrationID: "1025983137635915219-3562718380525399392-3",
+		Tags:           []string{"tag1", "tag2"},
+	}
+	if len(read) != 2 || read[0].Target != ApplePlatform || !reflect.DeepEqual(read[0].RegisteredDevice, expectedDevice) {
+		t.Errorf(errfmt, "export file", expectedDevice, read)
+	}
+	if read[1].RegisteredDevice.DeviceID != "ANDROIDID" || read[1].Format != Template {
+		t.Errorf(errfmt, "export file template registration", "ANDROIDID", read[1].RegisteredDevice)
+	}
+}
@@ -0,0 +1,181 @@
+package notificationhubs
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/daresaydigital/azure-notificationhubs-go/utils"
+)
+
+const (
+	redacted               = "REDACTED"
+	defaultLogBodyLength   = 256
+	logDeviceHandleLength  = 8
+	deviceHandleHeaderName = "ServiceBusNotification-DeviceHandle"
+)
+
+// LogDebug and the other levels of a log entry
+const (
+	LogDebug LogLevel = iota
+	LogInfo
+	LogWarn
+	LogError
+)
+
+var (
+	signaturePattern       = regexp.MustCompile(`(?i)(sig=)[^&\s"';]+`)
+	sharedAccessKeyPattern = regexp.MustCompile(`(?i)(SharedAccessKey=)[^;\s"'&]+`)
+)
+
+type (
+	// LogLevel is the severity of a log entry
+	LogLevel int
+
+	// Logger writes structured log entries, keyvals are alternating keys and values
+	Logger interface {
+		Log(ctx context.Context, level LogLevel, msg string, keyvals ...interface{})
+	}
+
+	// LoggingOptions configures the request logging
+	LoggingOptions struct {
+		// Level is the lowest level logged, successful requests are logged at LogDebug,
+		// 4xx responses at LogWarn and 5xx responses and transport errors at LogError
+		Level LogLevel
+		// Headers logs the request headers, the Authorization header is always redacted
+		// and the device handle truncated to its first 8 bytes
+		Headers bool
+		// Bodies logs the request and response bodies
+		Bodies bool
+		// MaxBodyLength truncates the logged bodies, defaults to 256 bytes, negative to log bodies in full
+		MaxBodyLength int
+	}
+)
+
+// SetLogger logs a summary of every hub request, with secrets redacted
+func (h *NotificationHub) SetLogger(l Logger, opts *LoggingOptions) {
+	if l == nil {
+		h.logging = nil
+		return
+	}
+	h.logging = LoggingInterceptor(l, opts)
+}
+
+// LoggingInterceptor logs a summary of every request, with secrets redacted
+func LoggingInterceptor(l Logger, opts *LoggingOptions) utils.Interceptor {
+	var o LoggingOptions
+	if opts != nil {
+		o = *opts
+	}
+	if o.MaxBodyLength == 0 {
+		o.MaxBodyLength = defaultLogBodyLength
+	}
+
+	return func(next utils.HTTPClient) utils.HTTPClient {
+		return utils.HTTPClientFunc(func(req *http.Request) ([]byte, *http.Response, error) {
+			// the transport closes the request body, a pooled batch body can not be copied afterwards
+			var body string
+			if o.Bodies {
+				body = requestBody(req)
+			}
+			started := time.Now()
+			raw, response, err := next.Exec(req)
+
+			statusCode := responseStatusCode(response, err)
+			level := LogDebug
+			switch {
+			case statusCode >= 500 || err != nil && statusCode == 0:
+				level = LogError
+			case statusCode >= 400:
+				level = LogWarn
+			}
+			if level < o.Level {
+				return raw, response, err
+			}
+
+			keyvals := []interface{}{
+				"operation", utils.OperationFromContext(req.Context()),
+				"method", req.Method,
+				"url", Redact(req.URL.String()),
+				"status", statusCode,
+				"duration", time.Since(started),
+			}
+			if o.Headers {
+				keyvals = append(keyvals, "headers", redactHeaders(req.Header))
+			}
+			if o.Bodies {
+				keyvals = append(keyvals,
+					"requestBody", truncate(Redact(body), o.MaxBodyLength),
+					"responseBody", truncate(Redact(string(raw)), o.MaxBodyLength),
+				)
+			}
+			if err != nil {
+				keyvals = append(keyvals, "error", truncate(Redact(err.Error()), o.MaxBodyLength))
+			}
+			l.Log(req.Context(), level, "notificationhubs request", keyvals...)
+			return raw, response, err
+		})
+	}
+}
+
+// Redact replaces SAS signatures and shared access keys, as in URLs, tokens and connection strings
+func Redact(s string) string {
+	s = signaturePattern.ReplaceAllString(s, "${1}"+redacted)
+	return sharedAccessKeyPattern.ReplaceAllString(s, "${1}"+redacted)
+}
+
+// String returns the name of the level
+func (l LogLevel) String() string {
+	switch l {
+	case LogDebug:
+		return "debug"
+	case LogInfo:
+		return "info"
+	case LogWarn:
+		return "warn"
+	case LogError:
+		return "error"
+	}
+	return "level(" + strconv.Itoa(int(l)) + ")"
+}
+
+// redactHeaders copies the headers with the Authorization header redacted and the device handle truncated
+func redactHeaders(header http.Header) map[string]string {
+	headers := make(map[string]string, len(header))
+	for name := range header {
+		switch http.CanonicalHeaderKey(name) {
+		case "Authorization":
+			headers[name] = redacted
+		case http.CanonicalHeaderKey(deviceHandleHeaderName):
+			headers[name] = truncate(header.Get(name), logDeviceHandleLength)
+		default:
+			headers[name] = Redact(header.Get(name))
+		}
+	}
+	return headers
+}
+
+// requestBody reads a copy of the request body, or an empty string
+func requestBody(req *http.Request) string {
+	if req.GetBody == nil {
+		return ""
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return ""
+	}
+	defer body.Close()
+	b, _ := ioutil.ReadAll(body)
+	return string(b)
+}
+
+// truncate shortens s to max bytes, a negative max keeps s
+func truncate(s string, max int) string {
+	if max < 0 || len(s) <= max {
+		return s
+	}
+	return s[:max] + "...(" + strconv.Itoa(len(s)-max) + " more bytes)"
+}
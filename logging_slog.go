@@ -0,0 +1,38 @@
+//go:build go1.21
+// +build go1.21
+
+package notificationhubs
+
+import (
+	"context"
+	"log/slog"
+)
+
+type slogLogger struct {
+	logger *slog.Logger
+}
+
+// NewSlogLogger adapts a log/slog logger, nil uses the default logger
+func NewSlogLogger(l *slog.Logger) Logger {
+	if l == nil {
+		l = slog.Default()
+	}
+	return slogLogger{logger: l}
+}
+
+// Log writes the entry at the matching slog level
+func (l slogLogger) Log(ctx context.Context, level LogLevel, msg string, keyvals ...interface{}) {
+	l.logger.Log(ctx, slogLevel(level), msg, keyvals...)
+}
+
+func slogLevel(level LogLevel) slog.Level {
+	switch level {
+	case LogDebug:
+		return slog.LevelDebug
+	case LogInfo:
+		return slog.LevelInfo
+	case LogWarn:
+		return slog.LevelWarn
+	}
+	return slog.LevelError
+}
@@ -0,0 +1,28 @@
+//go:build go1.21
+// +build go1.21
+
+package notificationhubs_test
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+
+	. "github.com/daresaydigital/azure-notificationhubs-go"
+)
+
+func TestNewSlogLogger(t *testing.T) {
+	var (
+		buf    bytes.Buffer
+		logger = NewSlogLogger(slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelWarn})))
+	)
+
+	logger.Log(context.Background(), LogDebug, "hidden", "operation", "Send")
+	logger.Log(context.Background(), LogWarn, "notificationhubs request", "operation", "Send", "status", 401)
+
+	if output := buf.String(); strings.Contains(output, "hidden") || !strings.Contains(output, "level=WARN") || !strings.Contains(output, "operation=Send status=401") {
+		t.Errorf(errfmt, "slog output", "warn entry only", output)
+	}
+}
@@ -0,0 +1,168 @@
+package notificationhubs_test
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	. "github.com/daresaydigital/azure-notificationhubs-go"
+	"github.com/daresaydigital/azure-notificationhubs-go/utils"
+)
+
+type (
+	mockLogger struct {
+		entries []mockLogEntry
+	}
+
+	mockLogEntry struct {
+		level  LogLevel
+		fields map[string]interface{}
+	}
+)
+
+func (l *mockLogger) Log(ctx context.Context, level LogLevel, msg string, keyvals ...interface{}) {
+	fields := map[string]interface{}{}
+	for i := 0; i+1 < len(keyvals); i += 2 {
+		fields[keyvals[i].(string)] = keyvals[i+1]
+	}
+	l.entries = append(l.entries, mockLogEntry{level: level, fields: fields})
+}
+
+func TestRedact(t *testing.T) {
+	testCases := []struct {
+		input, expected string
+	}{
+		{
+			input:    "SharedAccessSignature sr=https%3a%2f%2ftesthub&sig=abc%2Bdef%3D&se=1&skn=DefaultFullSharedAccessSignature",
+			expected: "SharedAccessSignature sr=https%3a%2f%2ftesthub&sig=REDACTED&se=1&skn=DefaultFullSharedAccessSignature",
+		},
+		{
+			input:    "Endpoint=sb://testhub-ns.servicebus.windows.net/;SharedAccessKeyName=DefaultFullSharedAccessSignature;SharedAccessKey=secret=",
+			expected: "Endpoint=sb://testhub-ns.servicebus.windows.net/;SharedAccessKeyName=DefaultFullSharedAccessSignature;SharedAccessKey=REDACTED",
+		},
+		{
+			input:    "https://blob.core.windows.net/container?sv=2019&sig=secret%3D",
+			expected: "https://blob.core.windows.net/container?sv=2019&sig=REDACTED",
+		},
+	}
+	for _, testCase := range testCases {
+		if obtained := Redact(testCase.input); obtained != testCase.expected {
+			t.Errorf(errfmt, "Redact", testCase.expected, obtained)
+		}
+	}
+}
+
+func Test_SetLogger(t *testing.T) {
+	var (
+		nhub, mockClient = initTestItems()
+		logger           = &mockLogger{}
+		payload          = `{"aps":{"alert":"` + strings.Repeat("x", 100) + `"}}`
+		notification, _  = NewNotification(AppleFormat, []byte(payload))
+		fail             = false
+	)
+	nhub.SetLogger(logger, &LoggingOptions{Headers: true, Bodies: true, MaxBodyLength: 20})
+	nhub.Use(utils.UserAgentInterceptor("test-agent"))
+
+	mockClient.execFunc = func(req *http.Request) ([]byte, *http.Response, error) {
+		if fail {
+			return nil, nil, &utils.HTTPError{StatusCode: http.StatusUnauthorized, Body: []byte("sig=leaked")}
+		}
+		return nil, &http.Response{
+			StatusCode: http.StatusCreated,
+			Header: http.Header{
+				"Location": []string{"https://testhub-ns.servicebus.windows.net/testhub/messages/1?api-version=2016-07"},
+			},
+		}, nil
+	}
+
+	_, _, _ = nhub.Send(context.Background(), notification, nil)
+	fail = true
+	_, _, _ = nhub.Send(context.Background(), notification, nil)
+
+	if len(logger.entries) != 2 {
+		t.Fatalf(errfmt, "log entries", 2, len(logger.entries))
+	}
+
+	entry := logger.entries[0]
+	if entry.level != LogDebug || entry.fields["operation"] != "Send" || entry.fields["status"] != http.StatusCreated {
+		t.Errorf(errfmt, "success entry", "debug Send 201", entry)
+	}
+	headers := entry.fields["headers"].(map[string]string)
+	if headers["Authorization"] != "REDACTED" {
+		t.Errorf(errfmt, "Authorization", "REDACTED", headers["Authorization"])
+	}
+	if headers["User-Agent"] != "test-agent" {
+		t.Errorf(errfmt, "User-Agent", "test-agent", headers["User-Agent"])
+	}
+	if body := entry.fields["requestBody"].(string); !strings.HasPrefix(body, payload[:20]+"...(") {
+		t.Errorf(errfmt, "truncated requestBody", payload[:20]+"...", body)
+	}
+
+	entry = logger.entries[1]
+	if entry.level != LogWarn || entry.fields["status"] != http.StatusUnauthorized {
+		t.Errorf(errfmt, "failure entry", "warn 401", entry)
+	}
+	if logged := fmt.Sprint(entry.fields); strings.Contains(logged, "leaked") {
+		t.Errorf(errfmt, "redacted entry", "no signature", logged)
+	}
+
+	nhub.SetLogger(logger, &LoggingOptions{Level: LogError})
+	_, _, _ = nhub.Send(context.Background(), notification, nil)
+	if len(logger.entries) != 2 {
+		t.Errorf(errfmt, "filtered log entries", 2, len(logger.entries))
+	}
+}
+
+func Test_SetLoggerDeviceHandle(t *testing.T) {
+	var (
+		nhub, mockClient = initTestItems()
+		logger           = &mockLogger{}
+		deviceHandle     = strings.Repeat("ab", 32)
+		notification, _  = NewNotification(AppleFormat, []byte("{}"))
+	)
+	nhub.SetLogger(logger, &LoggingOptions{Headers: true})
+	mockClient.execFunc = func(req *http.Request) ([]byte, *http.Response, error) {
+		return nil, nil, nil
+	}
+
+	_, _, _ = nhub.SendDirect(context.Background(), notification, deviceHandle)
+	if len(logger.entries) != 1 {
+		t.Fatalf(errfmt, "log entries", 1, len(logger.entries))
+	}
+	headers := logger.entries[0].fields["headers"].(map[string]string)
+	var logged string
+	for name, value := range headers {
+		if http.CanonicalHeaderKey(name) == http.CanonicalHeaderKey("ServiceBusNotification-DeviceHandle") {
+			logged = value
+		}
+	}
+	if logged != "abababab...(56 more bytes)" {
+		t.Errorf(errfmt, "device handle", "abababab...(56 more bytes)", logged)
+	}
+}
+
+func Test_SetLoggerBatchBody(t *testing.T) {
+	var (
+		nhub, mockClient = initTestItems()
+		logger           = &mockLogger{}
+		notification, _  = NewNotification(AppleFormat, []byte(`{"aps":{"alert":"hello"}}`))
+	)
+	nhub.SetLogger(logger, &LoggingOptions{Bodies: true, MaxBodyLength: -1})
+	mockClient.execFunc = func(req *http.Request) ([]byte, *http.Response, error) {
+		// the transport closes the body once it is sent
+		_, _ = ioutil.ReadAll(req.Body)
+		_ = req.Body.Close()
+		return nil, nil, nil
+	}
+
+	_, _, _ = nhub.SendDirectBatch(context.Background(), notification, "ABCDEFG", "HIJKLMN")
+	if len(logger.entries) != 1 {
+		t.Fatalf(errfmt, "log entries", 1, len(logger.entries))
+	}
+	if body, _ := logger.entries[0].fields["requestBody"].(string); !strings.Contains(body, `["ABCDEFG","HIJKLMN"]`) {
+		t.Errorf(errfmt, "requestBody", `["ABCDEFG","HIJKLMN"]`, body)
+	}
+}
@@ -0,0 +1,79 @@
+package notificationhubs
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/daresaydigital/azure-notificationhubs-go/utils"
+)
+
+type (
+	// Metrics receives the measurements of the hub requests
+	Metrics interface {
+		// ObserveRequest is called after every hub request, the status code is 0 if no response was received
+		ObserveRequest(operation string, statusCode int, duration time.Duration, requestBytes, responseBytes int)
+		// ObserveRetry is called for every request which is a retry of an earlier attempt
+		ObserveRetry(operation string)
+		// ObserveThrottle is called for every throttled request
+		ObserveThrottle(operation string)
+		// ObserveSend is called for every notification sent, scheduled or sent directly
+		ObserveSend(format NotificationFormat)
+	}
+
+	// metricsContextKey is the type of the context keys of the metrics
+	metricsContextKey int
+)
+
+const retryAttemptKey metricsContextKey = iota
+
+// SetMetrics reports the measurements of every hub request to m
+func (h *NotificationHub) SetMetrics(m Metrics) {
+	h.metrics = m
+}
+
+// WithRetryAttempt returns a context marking the requests as the attempt-th attempt of a call,
+// attempts after the first are reported as retries
+func WithRetryAttempt(ctx context.Context, attempt int) context.Context {
+	return context.WithValue(ctx, retryAttemptKey, attempt)
+}
+
+// observeRequest reports a finished hub request
+func (h *NotificationHub) observeRequest(ctx context.Context, operation string, started time.Time, requestBytes int, raw []byte, response *http.Response, err error) {
+	if attempt, ok := ctx.Value(retryAttemptKey).(int); ok && attempt > 1 {
+		h.metrics.ObserveRetry(operation)
+	}
+	if isThrottled(err) {
+		h.metrics.ObserveThrottle(operation)
+	}
+	h.metrics.ObserveRequest(operation, responseStatusCode(response, err), time.Since(started), requestBytes, len(raw))
+}
+
+// observeSend reports a notification sent
+func (h *NotificationHub) observeSend(format NotificationFormat) {
+	if h.metrics != nil {
+		h.metrics.ObserveSend(format)
+	}
+}
+
+// readerLen returns the length of a buffered request body, or 0
+func readerLen(r io.Reader) int {
+	if sized, ok := r.(interface{ Len() int }); ok {
+		return sized.Len()
+	}
+	return 0
+}
+
+// responseStatusCode returns the status code of the response or of the unexpected status error, or 0
+func responseStatusCode(response *http.Response, err error) int {
+	var httpErr *utils.HTTPError
+	switch {
+	case response != nil:
+		return response.StatusCode
+	case errors.As(err, &httpErr):
+		return httpErr.StatusCode
+	}
+	return 0
+}
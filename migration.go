@@ -0,0 +1,398 @@
+package notificationhubs
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	registrationCheckpointPrefix = "registration:"
+	installationCheckpointPrefix = "installation:"
+)
+
+type (
+	// MigrationOptions configures what a Migrator copies and how
+	MigrationOptions struct {
+		// InstallationIDs are the installations to copy, since installations can not be listed through the API
+		InstallationIDs []string
+		// SkipRegistrations copies the installations only
+		SkipRegistrations bool
+		// MapTags rewrites the tags of every copied device and template, or nil to keep the tags
+		MapTags func(tags []string) []string
+		// DryRun reads the source and reports what would be copied without writing to the target
+		DryRun bool
+		// Checkpoint records the copied devices so an interrupted migration can be resumed, or nil
+		Checkpoint MigrationCheckpoint
+		// Concurrency is the maximum number of devices copied at once, defaults to 4
+		Concurrency int
+	}
+
+	// MigrationCheckpoint records which devices have been copied
+	// and the target registration IDs reserved for registrations being copied, so a resumed copy
+	// replaces the registration written before an interruption instead of creating another one
+	MigrationCheckpoint interface {
+		Done(key string) (bool, error)
+		MarkDone(key string) error
+		TargetID(key string) (string, error)
+		SetTargetID(key, targetID string) error
+	}
+
+	// FileCheckpoint is a MigrationCheckpoint appending the copied devices to a file
+	FileCheckpoint struct {
+		mu        sync.Mutex
+		file      *os.File
+		done      map[string]bool
+		targetIDs map[string]string
+	}
+
+	// MigrationReport lists the copied, skipped and failed devices by checkpoint key,
+	// such as "registration:{id}" or "installation:{id}"
+	MigrationReport struct {
+		DryRun  bool
+		Copied  []string
+		Skipped []string
+		Failed  map[string]error
+
+		mu sync.Mutex
+	}
+
+	// Migrator copies registrations and installations from one hub to another
+	Migrator struct {
+		source *NotificationHub
+		target *NotificationHub
+		opts   MigrationOptions
+	}
+
+	// migrationItem is a device to copy
+	migrationItem struct {
+		key          string
+		registration *RegistrationResult
+	}
+)
+
+// NewMigrator initializes and returns Migrator pointer
+func NewMigrator(source, target *NotificationHub, opts *MigrationOptions) *Migrator {
+	m := &Migrator{source: source, target: target}
+	if opts != nil {
+		m.opts = *opts
+	}
+	return m
+}
+
+// Run copies the devices and reports the outcome for every device
+// An error is returned if the registrations could not be listed or if any device failed
+func (m *Migrator) Run(ctx context.Context) (*MigrationReport, error) {
+	var (
+		report = &MigrationReport{DryRun: m.opts.DryRun, Failed: map[string]error{}}
+		items  = make(chan migrationItem)
+		wg     sync.WaitGroup
+	)
+
+	concurrency := m.opts.Concurrency
+	if concurrency < 1 {
+		concurrency = defaultFanoutConcurrency
+	}
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for item := range items {
+				m.migrate(ctx, item, report)
+			}
+		}()
+	}
+
+	var err error
+	if !m.opts.SkipRegistrations {
+		err = m.source.ForEachRegistration(ctx, func(r RegistrationResult) error {
+			if r.RegistrationContent == nil || r.RegistrationContent.RegisteredDevice == nil {
+				return nil
+			}
+			key := registrationCheckpointPrefix + r.RegistrationContent.RegisteredDevice.RegistrationID
+			select {
+			case items <- migrationItem{key: key, registration: &r}:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		})
+	}
+	for _, installationID := range m.opts.InstallationIDs {
+		if err != nil {
+			break
+		}
+		select {
+		case items <- migrationItem{key: installationCheckpointPrefix + installationID}:
+		case <-ctx.Done():
+			err = ctx.Err()
+		}
+	}
+	close(items)
+	wg.Wait()
+
+	report.sort()
+	if err != nil {
+		return report, err
+	}
+	if len(report.Failed) > 0 {
+		return report, fmt.Errorf("%d devices could not be copied", len(report.Failed))
+	}
+	return report, nil
+}
+
+// migrate copies one device unless it was already copied
+func (m *Migrator) migrate(ctx context.Context, item migrationItem, report *MigrationReport) {
+	if m.opts.Checkpoint != nil {
+		done, err := m.opts.Checkpoint.Done(item.key)
+		if err != nil {
+			report.fail(item.key, err)
+			return
+		}
+		if done {
+			report.skip(item.key)
+			return
+		}
+	}
+
+	var (
+		copied bool
+		err    error
+	)
+	if item.registration != nil {
+		copied, err = m.copyRegistration(ctx, item.key, *item.registration)
+	} else {
+		copied, err = m.copyInstallation(ctx, strings.TrimPrefix(item.key, installationCheckpointPrefix))
+	}
+	switch {
+	case err != nil:
+		report.fail(item.key, err)
+	case !copied:
+		report.skip(item.key)
+	default:
+		if m.opts.Checkpoint != nil && !m.opts.DryRun {
+			if err = m.opts.Checkpoint.MarkDone(item.key); err != nil {
+				report.fail(item.key, err)
+				return
+			}
+		}
+		report.copy(item.key)
+	}
+}
+
+// copyRegistration registers the device in the target, unsupported platforms are not copied
+// With a checkpoint the registration is written with a reserved target registration ID,
+// so a copy resumed after an interruption replaces it instead of registering the device twice
+func (m *Migrator) copyRegistration(ctx context.Context, key string, r RegistrationResult) (bool, error) {
+	var (
+		content = r.RegistrationContent
+		device  = content.RegisteredDevice
+		tags    = strings.Join(m.mapTags(device.Tags), ",")
+	)
+	switch content.Target {
+	case ApplePlatform, GcmPlatform, AppleTemplatePlatform, GcmTemplatePlatform:
+	default:
+		return false, nil
+	}
+	if m.opts.DryRun {
+		return true, nil
+	}
+
+	targetID, err := m.reserveTargetID(ctx, key)
+	if err != nil {
+		return false, err
+	}
+	switch content.Target {
+	case ApplePlatform, GcmPlatform:
+		_, _, err = m.target.Register(ctx, Registration{
+			DeviceID:           device.DeviceID,
+			NotificationFormat: content.Format,
+			RegistrationID:     targetID,
+			Tags:               tags,
+		})
+	default:
+		platform := ApplePlatform
+		if content.Target == GcmTemplatePlatform {
+			platform = GcmPlatform
+		}
+		_, _, err = m.target.RegisterWithTemplate(ctx, TemplateRegistration{
+			DeviceID:       device.DeviceID,
+			RegistrationID: targetID,
+			Tags:           tags,
+			Platform:       platform,
+			Template:       device.Template,
+		})
+	}
+	return err == nil, err
+}
+
+// reserveTargetID returns the target registration ID recorded for a registration,
+// or reserves and records a new one. Without a checkpoint the target assigns the ID when registering
+func (m *Migrator) reserveTargetID(ctx context.Context, key string) (string, error) {
+	if m.opts.Checkpoint == nil {
+		return "", nil
+	}
+	targetID, err := m.opts.Checkpoint.TargetID(key)
+	if err != nil || targetID != "" {
+		return targetID, err
+	}
+	if targetID, err = m.target.CreateRegistrationID(ctx); err != nil {
+		return "", err
+	}
+	return targetID, m.opts.Checkpoint.SetTargetID(key, targetID)
+}
+
+// copyInstallation reads the installation from the source and installs it in the target
+func (m *Migrator) copyInstallation(ctx context.Context, installationID string) (bool, error) {
+	_, installation, err := m.source.Installation(ctx, installationID)
+	if err != nil {
+		return false, err
+	}
+	if installation == nil {
+		return false, errors.New("installation not found")
+	}
+	installation.Tags = m.mapTags(installation.Tags)
+	for name, template := range installation.Templates {
+		template.Tags = m.mapTags(template.Tags)
+		installation.Templates[name] = template
+	}
+	if m.opts.DryRun {
+		return true, nil
+	}
+	if err = m.target.Install(ctx, *installation); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// mapTags rewrites the tags if a mapping is configured
+func (m *Migrator) mapTags(tags []string) []string {
+	if m.opts.MapTags == nil || len(tags) == 0 {
+		return tags
+	}
+	return m.opts.MapTags(tags)
+}
+
+func (r *MigrationReport) copy(key string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Copied = append(r.Copied, key)
+}
+
+func (r *MigrationReport) skip(key string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Skipped = append(r.Skipped, key)
+}
+
+func (r *MigrationReport) fail(key string, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Failed[key] = err
+}
+
+func (r *MigrationReport) sort() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	sort.Strings(r.Copied)
+	sort.Strings(r.Skipped)
+}
+
+// String summarizes the report, listing the failed devices
+func (r *MigrationReport) String() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var b strings.Builder
+	verb := "copied"
+	if r.DryRun {
+		verb = "would copy"
+	}
+	fmt.Fprintf(&b, "%s: %d, skipped: %d, failed: %d\n", verb, len(r.Copied), len(r.Skipped), len(r.Failed))
+	keys := make([]string, 0, len(r.Failed))
+	for key := range r.Failed {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		fmt.Fprintf(&b, "failed %s: %s\n", key, r.Failed[key])
+	}
+	return b.String()
+}
+
+// NewFileCheckpoint opens or creates a checkpoint file, reading the devices already copied
+// Every line is the key of a copied device, or a key and the target registration ID reserved for it
+func NewFileCheckpoint(path string) (*FileCheckpoint, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	c := &FileCheckpoint{file: file, done: map[string]bool{}, targetIDs: map[string]string{}}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		switch fields := strings.Fields(scanner.Text()); len(fields) {
+		case 1:
+			c.done[fields[0]] = true
+		case 2:
+			c.targetIDs[fields[0]] = fields[1]
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// Done identifies whether the device has been copied
+func (c *FileCheckpoint) Done(key string) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.done[key], nil
+}
+
+// MarkDone records the device as copied
+func (c *FileCheckpoint) MarkDone(key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.append(key); err != nil {
+		return err
+	}
+	c.done[key] = true
+	return nil
+}
+
+// TargetID returns the target registration ID reserved for the device, or an empty string
+func (c *FileCheckpoint) TargetID(key string) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.targetIDs[key], nil
+}
+
+// SetTargetID records the target registration ID reserved for the device
+func (c *FileCheckpoint) SetTargetID(key, targetID string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.append(key + " " + targetID); err != nil {
+		return err
+	}
+	c.targetIDs[key] = targetID
+	return nil
+}
+
+// Close closes the checkpoint file
+func (c *FileCheckpoint) Close() error {
+	return c.file.Close()
+}
+
+// append writes a line to the checkpoint file
+func (c *FileCheckpoint) append(line string) error {
+	if _, err := c.file.WriteString(line + "\n"); err != nil {
+		return err
+	}
+	return c.file.Sync()
+}
@@ -0,0 +1,233 @@
+package notificationhubs_test
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	. "github.com/daresaydigital/azure-notificationhubs-go"
+)
+
+const migrationInstallationID = "0a92196c-20c3-4308-8046-c384c902d0ff"
+
+func initMigrationTestItems(requests *[]string) (source, target *NotificationHub) {
+	var (
+		mu                      sync.Mutex
+		sourceHub, sourceClient = initTestItems()
+		targetHub, targetClient = initTestItems()
+	)
+
+	sourceClient.execFunc = func(req *http.Request) ([]byte, *http.Response, error) {
+		fixture := "./fixtures/registrationsResult.xml"
+		if strings.Contains(req.URL.Path, "/installations/") {
+			fixture = "./fixtures/gcmInstallationResult.json"
+		}
+		data, err := ioutil.ReadFile(fixture)
+		return data, nil, err
+	}
+	targetClient.execFunc = func(req *http.Request) ([]byte, *http.Response, error) {
+		var body []byte
+		if req.Body != nil {
+			body, _ = ioutil.ReadAll(req.Body)
+		}
+		mu.Lock()
+		*requests = append(*requests, req.Method+" "+req.URL.Path+" "+string(body))
+		reserved := len(*requests)
+		mu.Unlock()
+		switch {
+		case strings.HasSuffix(req.URL.Path, "/registrationIDs"):
+			return nil, &http.Response{
+				StatusCode: http.StatusCreated,
+				Header: http.Header{"Location": []string{
+					"https://testhub-ns.servicebus.windows.net/testhub/registrations/reserved-" + strconv.Itoa(reserved) + "?api-version=2015-01",
+				}},
+			}, nil
+		case strings.Contains(req.URL.Path, "/installations/"):
+			return nil, nil, nil
+		case strings.Contains(string(body), "ANDROIDID"):
+			return nil, nil, errors.New("test error")
+		default:
+			data, err := ioutil.ReadFile("./fixtures/appleRegistrationResult.xml")
+			return data, nil, err
+		}
+	}
+	return sourceHub, targetHub
+}
+
+func Test_MigratorRun(t *testing.T) {
+	var (
+		requests       []string
+		source, target = initMigrationTestItems(&requests)
+		dir, _         = ioutil.TempDir("", "migration")
+		checkpointPath = filepath.Join(dir, "checkpoint")
+	)
+	defer os.RemoveAll(dir)
+
+	if err := ioutil.WriteFile(checkpointPath, []byte("registration:2860736071967499721-3950266781525758710-1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	checkpoint, err := NewFileCheckpoint(checkpointPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := NewMigrator(source, target, &MigrationOptions{
+		InstallationIDs: []string{migrationInstallationID},
+		MapTags: func(tags []string) []string {
+			mapped := make([]string, len(tags))
+			for i, tag := range tags {
+				mapped[i] = "migrated_" + tag
+			}
+			return mapped
+		},
+		Checkpoint:  checkpoint,
+		Concurrency: 2,
+	}).Run(context.Background())
+	checkpoint.Close()
+	if err == nil {
+		t.Errorf(errfmt, "Run error", "failed devices", err)
+	}
+
+	wantCopied := []string{
+		"installation:" + migrationInstallationID,
+		"registration:1025983137635915219-3562718380525399392-3",
+		"registration:3288835312934927344-986564390439048203-1",
+	}
+	if !reflect.DeepEqual(report.Copied, wantCopied) {
+		t.Errorf(errfmt, "Copied", wantCopied, report.Copied)
+	}
+	wantSkipped := []string{"registration:2860736071967499721-3950266781525758710-1"}
+	if !reflect.DeepEqual(report.Skipped, wantSkipped) {
+		t.Errorf(errfmt, "Skipped", wantSkipped, report.Skipped)
+	}
+	if _, ok := report.Failed["registration:4603854756731398046-26535929789529194-1"]; !ok || len(report.Failed) != 1 {
+		t.Errorf(errfmt, "Failed", "gcm registration", report.Failed)
+	}
+
+	// every registration is written with a reserved ID, the installation is written once
+	if len(requests) != 7 {
+		t.Fatalf(errfmt, "target requests", 7, len(requests))
+	}
+	for _, request := range requests {
+		if strings.Contains(request, "tag1") && !strings.Contains(request, "migrated_tag1") {
+			t.Errorf(errfmt, "mapped tags", "migrated_tag1", request)
+		}
+		if strings.HasPrefix(request, "POST /testhub/registrations ") {
+			t.Errorf(errfmt, "registration write", "PUT with a reserved ID", request)
+		}
+	}
+
+	data, _ := ioutil.ReadFile(checkpointPath)
+	for _, key := range wantCopied {
+		if !strings.Contains(string(data), key) {
+			t.Errorf(errfmt, "checkpoint", key, string(data))
+		}
+	}
+}
+
+func Test_MigratorRunDryRun(t *testing.T) {
+	var (
+		requests       []string
+		source, target = initMigrationTestItems(&requests)
+	)
+
+	report, err := NewMigrator(source, target, &MigrationOptions{
+		InstallationIDs: []string{migrationInstallationID},
+		DryRun:          true,
+	}).Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(requests) != 0 {
+		t.Errorf(errfmt, "target requests", 0, len(requests))
+	}
+	if len(report.Copied) != 5 {
+		t.Errorf(errfmt, "Copied", 5, len(report.Copied))
+	}
+	if !strings.HasPrefix(report.String(), "would copy: 5, skipped: 0, failed: 0") {
+		t.Errorf(errfmt, "String", "would copy: 5, skipped: 0, failed: 0", report.String())
+	}
+}
+
+// failingCheckpoint fails the first MarkDone, as an interruption after the device was copied
+type failingCheckpoint struct {
+	*FileCheckpoint
+	failed bool
+}
+
+func (c *failingCheckpoint) MarkDone(key string) error {
+	if !c.failed {
+		c.failed = true
+		return errors.New("test error")
+	}
+	return c.FileCheckpoint.MarkDone(key)
+}
+
+func Test_MigratorResume(t *testing.T) {
+	var (
+		requests       []string
+		source, target = initMigrationTestItems(&requests)
+		dir, _         = ioutil.TempDir("", "migration")
+		checkpointPath = filepath.Join(dir, "checkpoint")
+		registrationID = "1025983137635915219-3562718380525399392-3"
+	)
+	defer os.RemoveAll(dir)
+
+	// the other registrations of registrationsResult.xml are already copied
+	done := []string{
+		"registration:2860736071967499721-3950266781525758710-1",
+		"registration:3288835312934927344-986564390439048203-1",
+		"registration:4603854756731398046-26535929789529194-1",
+	}
+	if err := ioutil.WriteFile(checkpointPath, []byte(strings.Join(done, "\n")+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	run := func(checkpoint MigrationCheckpoint) *MigrationReport {
+		report, _ := NewMigrator(source, target, &MigrationOptions{Checkpoint: checkpoint}).Run(context.Background())
+		return report
+	}
+
+	fileCheckpoint, err := NewFileCheckpoint(checkpointPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	report := run(&failingCheckpoint{FileCheckpoint: fileCheckpoint})
+	fileCheckpoint.Close()
+	if _, ok := report.Failed["registration:"+registrationID]; !ok {
+		t.Fatalf(errfmt, "Failed", "MarkDone error", report.Failed)
+	}
+
+	// the resumed run replaces the registration written before the failure
+	fileCheckpoint, err = NewFileCheckpoint(checkpointPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fileCheckpoint.Close()
+	report = run(fileCheckpoint)
+	if !reflect.DeepEqual(report.Copied, []string{"registration:" + registrationID}) {
+		t.Errorf(errfmt, "Copied", registrationID, report.Copied)
+	}
+
+	var writes []string
+	for _, request := range requests {
+		fields := strings.SplitN(request, " ", 3)
+		writes = append(writes, fields[0]+" "+fields[1])
+	}
+	wantWrites := []string{
+		"POST /testhub/registrationIDs",
+		"PUT /testhub/registrations/reserved-1",
+		"PUT /testhub/registrations/reserved-1",
+	}
+	if !reflect.DeepEqual(writes, wantWrites) {
+		t.Errorf(errfmt, "target requests", wantWrites, writes)
+	}
+}
@@ -0,0 +1,405 @@
+package notificationhubs
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MirrorPolicy decides how a MirroredHub treats failed writes to the secondary hub
+type MirrorPolicy string
+
+const (
+	// MirrorIgnore drops secondary failures
+	MirrorIgnore MirrorPolicy = "ignore"
+	// MirrorLog reports secondary failures to the error handler and succeeds
+	MirrorLog MirrorPolicy = "log"
+	// MirrorFail returns secondary failures to the caller
+	MirrorFail MirrorPolicy = "fail"
+)
+
+// ErrNoSecondaryRegistration is the secondary failure of a registration update or removal
+// when the registration ID store has no secondary registration for the primary registration
+var ErrNoSecondaryRegistration = errors.New("no secondary registration known for the primary registration")
+
+type (
+	// MirrorOptions configures a MirroredHub
+	MirrorOptions struct {
+		// Policy is the treatment of secondary failures, defaults to MirrorLog
+		Policy MirrorPolicy
+		// OnSecondaryError receives the secondary failures under MirrorLog, or nil
+		OnSecondaryError func(operation string, err error)
+		// Logger logs the secondary failures under MirrorLog when OnSecondaryError is nil,
+		// the failures are dropped if both are nil
+		Logger Logger
+		// RegistrationIDs maps the primary registration IDs to the secondary ones,
+		// defaults to a MemoryRegistrationIDStore which is lost when the process exits
+		RegistrationIDs RegistrationIDStore
+	}
+
+	// RegistrationIDStore maps primary registration IDs to the secondary registration IDs mirroring them
+	RegistrationIDStore interface {
+		SecondaryID(primaryID string) (secondaryID string, ok bool, err error)
+		SetSecondaryID(primaryID, secondaryID string) error
+		DeleteSecondaryID(primaryID string) error
+	}
+
+	// MemoryRegistrationIDStore is a RegistrationIDStore kept in memory
+	MemoryRegistrationIDStore struct {
+		mu  sync.RWMutex
+		ids map[string]string
+	}
+
+	// FileRegistrationIDStore is a RegistrationIDStore appending the changes to a file
+	FileRegistrationIDStore struct {
+		mu   sync.RWMutex
+		file *os.File
+		ids  map[string]string
+	}
+
+	// MirrorError is a failed write to the secondary hub under MirrorFail
+	// The write to the primary hub succeeded
+	MirrorError struct {
+		Operation string
+		Err       error
+	}
+
+	// MirroredHub writes installations and registrations to a primary and a secondary hub
+	// while reads and sends are served by one of them, the primary until SwitchSends is called
+	MirroredHub struct {
+		primary   *NotificationHub
+		secondary *NotificationHub
+		opts      MirrorOptions
+
+		mu              sync.RWMutex
+		sendToSecondary bool
+	}
+)
+
+// NewMirroredHub initializes and returns MirroredHub pointer
+func NewMirroredHub(primary, secondary *NotificationHub, opts *MirrorOptions) *MirroredHub {
+	m := &MirroredHub{
+		primary:   primary,
+		secondary: secondary,
+	}
+	if opts != nil {
+		m.opts = *opts
+	}
+	if m.opts.Policy == "" {
+		m.opts.Policy = MirrorLog
+	}
+	if m.opts.RegistrationIDs == nil {
+		m.opts.RegistrationIDs = NewMemoryRegistrationIDStore()
+	}
+	return m
+}
+
+// Error returns the failed secondary operation and its error
+func (e *MirrorError) Error() string {
+	return fmt.Sprintf("secondary hub %s failed: %v", e.Operation, e.Err)
+}
+
+// Primary returns the primary hub
+func (m *MirroredHub) Primary() *NotificationHub {
+	return m.primary
+}
+
+// Secondary returns the secondary hub
+func (m *MirroredHub) Secondary() *NotificationHub {
+	return m.secondary
+}
+
+// SwitchSends selects the hub serving sends and reads, the secondary if toSecondary is true
+func (m *MirroredHub) SwitchSends(toSecondary bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sendToSecondary = toSecondary
+}
+
+// SendHub returns the hub currently serving sends and reads
+func (m *MirroredHub) SendHub() *NotificationHub {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.sendToSecondary {
+		return m.secondary
+	}
+	return m.primary
+}
+
+// SecondaryRegistrationID returns the secondary registration ID mirroring a primary registration ID
+func (m *MirroredHub) SecondaryRegistrationID(primaryID string) (string, bool, error) {
+	return m.opts.RegistrationIDs.SecondaryID(primaryID)
+}
+
+// MapRegistrationID records the secondary registration ID mirroring a primary registration ID,
+// for registrations created before the MirroredHub was in use
+func (m *MirroredHub) MapRegistrationID(primaryID, secondaryID string) error {
+	return m.opts.RegistrationIDs.SetSecondaryID(primaryID, secondaryID)
+}
+
+// Send publishes a notification through the send hub
+func (m *MirroredHub) Send(ctx context.Context, n *Notification, tags *string) ([]byte, *NotificationTelemetry, error) {
+	return m.SendHub().Send(ctx, n, tags)
+}
+
+// SendDirect publishes a notification directly to a device through the send hub
+func (m *MirroredHub) SendDirect(ctx context.Context, n *Notification, deviceHandle string) ([]byte, *NotificationTelemetry, error) {
+	return m.SendHub().SendDirect(ctx, n, deviceHandle)
+}
+
+// Schedule schedules a notification through the send hub
+func (m *MirroredHub) Schedule(ctx context.Context, n *Notification, tags *string, deliverTime time.Time) ([]byte, *NotificationTelemetry, error) {
+	return m.SendHub().Schedule(ctx, n, tags, deliverTime)
+}
+
+// Installation reads an installation from the send hub
+func (m *MirroredHub) Installation(ctx context.Context, installationID string) ([]byte, *Installation, error) {
+	return m.SendHub().Installation(ctx, installationID)
+}
+
+// Registration reads a registration from the send hub
+func (m *MirroredHub) Registration(ctx context.Context, registrationID string) ([]byte, *RegistrationResult, error) {
+	return m.SendHub().Registration(ctx, registrationID)
+}
+
+// Install writes an installation to both hubs
+func (m *MirroredHub) Install(ctx context.Context, installation Installation) error {
+	if err := m.primary.Install(ctx, installation); err != nil {
+		return err
+	}
+	return m.secondaryResult(ctx, "install", m.secondary.Install(ctx, installation))
+}
+
+// Update applies installation changes on both hubs
+func (m *MirroredHub) Update(ctx context.Context, installationID string, changes ...InstallationChange) error {
+	if err := m.primary.Update(ctx, installationID, changes...); err != nil {
+		return err
+	}
+	return m.secondaryResult(ctx, "update", m.secondary.Update(ctx, installationID, changes...))
+}
+
+// Uninstall removes an installation from both hubs
+func (m *MirroredHub) Uninstall(ctx context.Context, installationID string) error {
+	if err := m.primary.Uninstall(ctx, installationID); err != nil {
+		return err
+	}
+	return m.secondaryResult(ctx, "uninstall", m.secondary.Uninstall(ctx, installationID))
+}
+
+// Register writes a registration to both hubs
+// The registration ID is the primary one, the secondary registration ID is tracked by the registration ID store.
+// An update of a registration missing from the store is not mirrored and fails with ErrNoSecondaryRegistration
+func (m *MirroredHub) Register(ctx context.Context, r Registration) ([]byte, *RegistrationResult, error) {
+	raw, result, err := m.primary.Register(ctx, r)
+	if err != nil {
+		return raw, result, err
+	}
+	secondary := r
+	if secondary.RegistrationID, err = m.secondaryRegistrationID(r.RegistrationID); err != nil {
+		return raw, result, m.secondaryResult(ctx, "register", err)
+	}
+	_, secondaryResult, err := m.secondary.Register(ctx, secondary)
+	if err == nil {
+		err = m.mapRegistration(result, secondaryResult)
+	}
+	return raw, result, m.secondaryResult(ctx, "register", err)
+}
+
+// RegisterWithTemplate writes a template registration to both hubs
+// The registration ID is the primary one, the secondary registration ID is tracked by the registration ID store.
+// An update of a registration missing from the store is not mirrored and fails with ErrNoSecondaryRegistration
+func (m *MirroredHub) RegisterWithTemplate(ctx context.Context, r TemplateRegistration) ([]byte, *RegistrationResult, error) {
+	raw, result, err := m.primary.RegisterWithTemplate(ctx, r)
+	if err != nil {
+		return raw, result, err
+	}
+	secondary := r
+	if secondary.RegistrationID, err = m.secondaryRegistrationID(r.RegistrationID); err != nil {
+		return raw, result, m.secondaryResult(ctx, "register", err)
+	}
+	_, secondaryResult, err := m.secondary.RegisterWithTemplate(ctx, secondary)
+	if err == nil {
+		err = m.mapRegistration(result, secondaryResult)
+	}
+	return raw, result, m.secondaryResult(ctx, "register", err)
+}
+
+// Unregister removes a registration from both hubs
+func (m *MirroredHub) Unregister(ctx context.Context, registration RegisteredDevice) error {
+	if err := m.primary.Unregister(ctx, registration); err != nil {
+		return err
+	}
+
+	secondaryID, err := m.secondaryRegistrationID(registration.RegistrationID)
+	if err != nil {
+		return m.secondaryResult(ctx, "unregister", err)
+	}
+
+	secondary := registration
+	secondary.RegistrationID = secondaryID
+	secondary.ETag = "*"
+	if err = m.secondary.Unregister(ctx, secondary); err == nil {
+		err = m.opts.RegistrationIDs.DeleteSecondaryID(registration.RegistrationID)
+	}
+	return m.secondaryResult(ctx, "unregister", err)
+}
+
+// secondaryRegistrationID returns the mirrored ID of an existing registration,
+// or an empty ID creating a new secondary registration when primaryID is empty
+func (m *MirroredHub) secondaryRegistrationID(primaryID string) (string, error) {
+	if primaryID == "" {
+		return "", nil
+	}
+	id, ok, err := m.SecondaryRegistrationID(primaryID)
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		return "", ErrNoSecondaryRegistration
+	}
+	return id, nil
+}
+
+// mapRegistration records the secondary registration ID of a successful write
+func (m *MirroredHub) mapRegistration(primary, secondary *RegistrationResult) error {
+	primaryID, secondaryID := registrationID(primary), registrationID(secondary)
+	if primaryID == "" || secondaryID == "" {
+		return nil
+	}
+	return m.MapRegistrationID(primaryID, secondaryID)
+}
+
+// secondaryResult applies the policy to the outcome of a secondary write
+func (m *MirroredHub) secondaryResult(ctx context.Context, operation string, err error) error {
+	if err == nil {
+		return nil
+	}
+	switch m.opts.Policy {
+	case MirrorIgnore:
+		return nil
+	case MirrorFail:
+		return &MirrorError{Operation: operation, Err: err}
+	default:
+		if m.opts.OnSecondaryError != nil {
+			m.opts.OnSecondaryError(operation, err)
+		} else if m.opts.Logger != nil {
+			m.opts.Logger.Log(ctx, LogWarn, "notificationhubs secondary hub write failed", "operation", operation, "error", err)
+		}
+		return nil
+	}
+}
+
+// registrationID returns the ID of a registration result or an empty string
+func registrationID(r *RegistrationResult) string {
+	if r == nil || r.RegistrationContent == nil || r.RegistrationContent.RegisteredDevice == nil {
+		return ""
+	}
+	return r.RegistrationContent.RegisteredDevice.RegistrationID
+}
+
+// NewMemoryRegistrationIDStore returns an empty MemoryRegistrationIDStore
+func NewMemoryRegistrationIDStore() *MemoryRegistrationIDStore {
+	return &MemoryRegistrationIDStore{ids: map[string]string{}}
+}
+
+// SecondaryID returns the secondary registration ID mirroring a primary registration ID
+func (s *MemoryRegistrationIDStore) SecondaryID(primaryID string) (string, bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	id, ok := s.ids[primaryID]
+	return id, ok, nil
+}
+
+// SetSecondaryID records the secondary registration ID mirroring a primary registration ID
+func (s *MemoryRegistrationIDStore) SetSecondaryID(primaryID, secondaryID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ids[primaryID] = secondaryID
+	return nil
+}
+
+// DeleteSecondaryID removes the secondary registration ID of a primary registration ID
+func (s *MemoryRegistrationIDStore) DeleteSecondaryID(primaryID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.ids, primaryID)
+	return nil
+}
+
+// NewFileRegistrationIDStore opens or creates a registration ID file, reading the IDs already mapped
+// Every line is a primary and a secondary registration ID, a primary ID alone removes its mapping
+func NewFileRegistrationIDStore(path string) (*FileRegistrationIDStore, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	s := &FileRegistrationIDStore{file: file, ids: map[string]string{}}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		switch fields := strings.Fields(scanner.Text()); len(fields) {
+		case 1:
+			delete(s.ids, fields[0])
+		case 2:
+			s.ids[fields[0]] = fields[1]
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// SecondaryID returns the secondary registration ID mirroring a primary registration ID
+func (s *FileRegistrationIDStore) SecondaryID(primaryID string) (string, bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	id, ok := s.ids[primaryID]
+	return id, ok, nil
+}
+
+// SetSecondaryID records the secondary registration ID mirroring a primary registration ID
+func (s *FileRegistrationIDStore) SetSecondaryID(primaryID, secondaryID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ids[primaryID] == secondaryID {
+		return nil
+	}
+	if err := s.append(primaryID + " " + secondaryID); err != nil {
+		return err
+	}
+	s.ids[primaryID] = secondaryID
+	return nil
+}
+
+// DeleteSecondaryID removes the secondary registration ID of a primary registration ID
+func (s *FileRegistrationIDStore) DeleteSecondaryID(primaryID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.ids[primaryID]; !ok {
+		return nil
+	}
+	if err := s.append(primaryID); err != nil {
+		return err
+	}
+	delete(s.ids, primaryID)
+	return nil
+}
+
+// Close closes the registration ID file
+func (s *FileRegistrationIDStore) Close() error {
+	return s.file.Close()
+}
+
+// append writes a line to the registration ID file
+func (s *FileRegistrationIDStore) append(line string) error {
+	if _, err := s.file.WriteString(line + "\n"); err != nil {
+		return err
+	}
+	return s.file.Sync()
+}
@@ -0,0 +1,231 @@
+package notificationhubs_test
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	. "github.com/daresaydigital/azure-notificationhubs-go"
+)
+
+const (
+	primaryRegistrationID   = "8247220326459738692-7748251457295609952-3"
+	secondaryRegistrationID = "5556163970238751145-4593285841060527077-1"
+)
+
+// initMirrorTestItems returns hubs answering registration writes with a fixture
+// and recording the requests, the secondary fails when secondaryErr is set
+func initMirrorTestItems(secondaryErr error) (primary, secondary *NotificationHub, primaryRequests, secondaryRequests *[]string) {
+	mock := func(fixture string, err error, requests *[]string) func(req *http.Request) ([]byte, *http.Response, error) {
+		return func(req *http.Request) ([]byte, *http.Response, error) {
+			*requests = append(*requests, req.Method+" "+req.URL.Path)
+			if err != nil {
+				return nil, nil, err
+			}
+			data, e := ioutil.ReadFile(fixture)
+			return data, nil, e
+		}
+	}
+
+	var (
+		primaryHub, primaryClient     = initTestItems()
+		secondaryHub, secondaryClient = initTestItems()
+	)
+	primaryRequests, secondaryRequests = &[]string{}, &[]string{}
+	primaryClient.execFunc = mock("./fixtures/appleRegistrationResult.xml", nil, primaryRequests)
+	secondaryClient.execFunc = mock("./fixtures/appleTemplateRegistrationResult.xml", secondaryErr, secondaryRequests)
+	return primaryHub, secondaryHub, primaryRequests, secondaryRequests
+}
+
+func Test_MirroredHubRegistrations(t *testing.T) {
+	var (
+		primary, secondary, primaryRequests, secondaryRequests = initMirrorTestItems(nil)
+		mirror                                                 = NewMirroredHub(primary, secondary, &MirrorOptions{Policy: MirrorFail})
+	)
+
+	_, result, err := mirror.Register(context.Background(), Registration{
+		DeviceID:           "ABCDEF",
+		NotificationFormat: AppleFormat,
+		Tags:               "tag1",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id := result.RegistrationContent.RegisteredDevice.RegistrationID; id != primaryRegistrationID {
+		t.Errorf(errfmt, "Register ID", primaryRegistrationID, id)
+	}
+	if id, _, _ := mirror.SecondaryRegistrationID(primaryRegistrationID); id != secondaryRegistrationID {
+		t.Errorf(errfmt, "SecondaryRegistrationID", secondaryRegistrationID, id)
+	}
+
+	if _, _, err = mirror.Register(context.Background(), Registration{
+		DeviceID:           "ABCDEF",
+		NotificationFormat: AppleFormat,
+		RegistrationID:     primaryRegistrationID,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err = mirror.Unregister(context.Background(), RegisteredDevice{RegistrationID: primaryRegistrationID, ETag: "1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	wantPrimary := []string{
+		"POST /testhub/registrations",
+		"PUT /testhub/registrations/" + primaryRegistrationID,
+		"DELETE /testhub/registrations/" + primaryRegistrationID,
+	}
+	if !reflect.DeepEqual(*primaryRequests, wantPrimary) {
+		t.Errorf(errfmt, "primary requests", wantPrimary, *primaryRequests)
+	}
+	wantSecondary := []string{
+		"POST /testhub/registrations",
+		"PUT /testhub/registrations/" + secondaryRegistrationID,
+		"DELETE /testhub/registrations/" + secondaryRegistrationID,
+	}
+	if !reflect.DeepEqual(*secondaryRequests, wantSecondary) {
+		t.Errorf(errfmt, "secondary requests", wantSecondary, *secondaryRequests)
+	}
+
+	err = mirror.Unregister(context.Background(), RegisteredDevice{RegistrationID: primaryRegistrationID})
+	if mirrorErr, ok := err.(*MirrorError); !ok || mirrorErr.Err != ErrNoSecondaryRegistration {
+		t.Errorf(errfmt, "Unregister unknown error", ErrNoSecondaryRegistration, err)
+	}
+}
+
+func Test_MirroredHubPolicies(t *testing.T) {
+	var (
+		expectedError = errors.New("test error")
+		installation  = Installation{InstallationID: "installation-1", Platform: APNSPlatform, PushChannel: "ABCDEF"}
+	)
+
+	primary, secondary, primaryRequests, _ := initMirrorTestItems(expectedError)
+	err := NewMirroredHub(primary, secondary, &MirrorOptions{Policy: MirrorFail}).Install(context.Background(), installation)
+	if mirrorErr, ok := err.(*MirrorError); !ok || mirrorErr.Operation != "install" || mirrorErr.Err != expectedError {
+		t.Errorf(errfmt, "MirrorFail error", expectedError, err)
+	}
+	if len(*primaryRequests) != 1 {
+		t.Errorf(errfmt, "primary requests", 1, len(*primaryRequests))
+	}
+
+	primary, secondary, _, _ = initMirrorTestItems(expectedError)
+	if err = NewMirroredHub(primary, secondary, &MirrorOptions{Policy: MirrorIgnore}).Uninstall(context.Background(), "installation-1"); err != nil {
+		t.Errorf(errfmt, "MirrorIgnore error", nil, err)
+	}
+
+	var logged []string
+	primary, secondary, _, _ = initMirrorTestItems(expectedError)
+	err = NewMirroredHub(primary, secondary, &MirrorOptions{
+		OnSecondaryError: func(operation string, err error) {
+			logged = append(logged, operation+": "+err.Error())
+		},
+	}).Update(context.Background(), "installation-1", AddTag("tag1"))
+	if err != nil {
+		t.Errorf(errfmt, "MirrorLog error", nil, err)
+	}
+	if !reflect.DeepEqual(logged, []string{"update: test error"}) {
+		t.Errorf(errfmt, "MirrorLog logged", []string{"update: test error"}, logged)
+	}
+}
+
+func Test_MirroredHubSwitchSends(t *testing.T) {
+	var (
+		primary, secondary, primaryRequests, secondaryRequests = initMirrorTestItems(nil)
+		mirror                                                 = NewMirroredHub(primary, secondary, nil)
+		notification, _                                        = NewNotification(Template, []byte("{}"))
+	)
+
+	if mirror.SendHub() != primary {
+		t.Errorf(errfmt, "SendHub", "primary", "secondary")
+	}
+	_, _, _ = mirror.Send(context.Background(), notification, nil)
+	mirror.SwitchSends(true)
+	if mirror.SendHub() != secondary {
+		t.Errorf(errfmt, "SendHub", "secondary", "primary")
+	}
+	_, _, _ = mirror.Send(context.Background(), notification, nil)
+
+	if len(*primaryRequests) != 1 || !strings.HasSuffix((*primaryRequests)[0], "/messages") {
+		t.Errorf(errfmt, "primary requests", "one send", *primaryRequests)
+	}
+	if len(*secondaryRequests) != 1 || !strings.HasSuffix((*secondaryRequests)[0], "/messages") {
+		t.Errorf(errfmt, "secondary requests", "one send", *secondaryRequests)
+	}
+}
+
+func Test_MirroredHubUnmappedUpdate(t *testing.T) {
+	var (
+		primary, secondary, primaryRequests, secondaryRequests = initMirrorTestItems(nil)
+		mirror                                                 = NewMirroredHub(primary, secondary, &MirrorOptions{Policy: MirrorFail})
+	)
+
+	_, _, err := mirror.RegisterWithTemplate(context.Background(), TemplateRegistration{
+		DeviceID:       "ABCDEF",
+		Platform:       ApplePlatform,
+		RegistrationID: primaryRegistrationID,
+		Template:       "{}",
+	})
+	if mirrorErr, ok := err.(*MirrorError); !ok || mirrorErr.Err != ErrNoSecondaryRegistration {
+		t.Errorf(errfmt, "unmapped update error", ErrNoSecondaryRegistration, err)
+	}
+	if len(*primaryRequests) != 1 {
+		t.Errorf(errfmt, "primary requests", 1, len(*primaryRequests))
+	}
+	if len(*secondaryRequests) != 0 {
+		t.Errorf(errfmt, "secondary requests", 0, *secondaryRequests)
+	}
+}
+
+func Test_MirroredHubFileRegistrationIDs(t *testing.T) {
+	var (
+		dir, _ = ioutil.TempDir("", "mirror")
+		path   = filepath.Join(dir, "registrations")
+	)
+	defer os.RemoveAll(dir)
+
+	store, err := NewFileRegistrationIDStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	primary, secondary, _, _ := initMirrorTestItems(nil)
+	mirror := NewMirroredHub(primary, secondary, &MirrorOptions{Policy: MirrorFail, RegistrationIDs: store})
+	if _, _, err = mirror.Register(context.Background(), Registration{DeviceID: "ABCDEF", NotificationFormat: AppleFormat}); err != nil {
+		t.Fatal(err)
+	}
+	if err = store.SetSecondaryID("removed", "removed-secondary"); err != nil {
+		t.Fatal(err)
+	}
+	if err = store.DeleteSecondaryID("removed"); err != nil {
+		t.Fatal(err)
+	}
+	store.Close()
+
+	// a restarted process updates the registration on the secondary hub instead of creating a new one
+	store, err = NewFileRegistrationIDStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	if _, ok, _ := store.SecondaryID("removed"); ok {
+		t.Errorf(errfmt, "removed ID", false, ok)
+	}
+
+	primary, secondary, _, secondaryRequests := initMirrorTestItems(nil)
+	mirror = NewMirroredHub(primary, secondary, &MirrorOptions{Policy: MirrorFail, RegistrationIDs: store})
+	if _, _, err = mirror.Register(context.Background(), Registration{
+		DeviceID:           "ABCDEF",
+		NotificationFormat: AppleFormat,
+		RegistrationID:     primaryRegistrationID,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"PUT /testhub/registrations/" + secondaryRegistrationID}
+	if !reflect.DeepEqual(*secondaryRequests, want) {
+		t.Errorf(errfmt, "secondary requests", want, *secondaryRequests)
+	}
+}
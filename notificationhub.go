@@ -5,12 +5,14 @@ import (
 	"crypto/hmac"
 	"crypto/sha256"
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
 	"path"
 	"strings"
+	"time"
 
 	"github.com/daresaydigital/azure-notificationhubs-go/utils"
 )
@@ -23,6 +25,11 @@ type NotificationHub struct {
 
 	client                  utils.HTTPClient
 	expirationTimeGenerator utils.ExpirationTimeGenerator
+	limiter                 *RateLimiter
+	interceptors            []utils.Interceptor
+	tracer                  Tracer
+	metrics                 Metrics
+	logging                 utils.Interceptor
 }
 
 // newNotificationHub initializes and returns NotificationHub pointer
@@ -107,18 +114,76 @@ func (h *NotificationHub) generateSasToken() string {
 	return fmt.Sprintf("SharedAccessSignature %s", tokenParams.Encode())
 }
 
-// exec request using method to url
-func (h *NotificationHub) exec(ctx context.Context, method string, url *url.URL, headers Headers, buf io.Reader) ([]byte, *http.Response, error) {
-	headers["Authorization"] = h.generateSasToken()
+// Use adds interceptors running around every hub request, the first added is the outermost
+// The name of the NotificationHub method of a request is available through utils.OperationFromContext
+func (h *NotificationHub) Use(interceptors ...utils.Interceptor) {
+	h.interceptors = append(h.interceptors, interceptors...)
+}
+
+// exec request of the named operation using method to url
+// With a rate limiter the request waits for its operation class, and throttled responses slow the class down
+func (h *NotificationHub) exec(ctx context.Context, operation, method string, url *url.URL, headers Headers, buf io.Reader) (raw []byte, response *http.Response, err error) {
+	return h.execRequest(ctx, operation, method, url, headers, buf, true)
+}
+
+// execBlob reads a blob referenced by the hub as the named operation
+// The blob URL carries its own shared access signature, so the request is not authorized with the hub SAS token.
+// The whole blob is read, a blob larger than the response body limit of the client fails with an error naming it
+func (h *NotificationHub) execBlob(ctx context.Context, operation string, blobURL *url.URL) ([]byte, error) {
+	raw, _, err := h.execRequest(ctx, operation, getMethod, blobURL, Headers{}, nil, false)
+	var sizeErr *utils.ResponseTooLargeError
+	if errors.As(err, &sizeErr) {
+		return nil, fmt.Errorf("%s: blob is larger than the %d bytes response limit, raise HubHTTPClientOptions.MaxResponseBodySize to read it: %w", operation, sizeErr.Limit, err)
+	}
+	return raw, err
+}
+
+// execRequest sends a request through the tracing, metrics, rate limiting and interceptors,
+// authorized with the hub SAS token if authorize is set
+func (h *NotificationHub) execRequest(ctx context.Context, operation, method string, url *url.URL, headers Headers, buf io.Reader, authorize bool) (raw []byte, response *http.Response, err error) {
+	if h.tracer != nil {
+		var span Span
+		ctx, span = h.startSpan(ctx, operation, headers)
+		defer func() { endSpan(span, response, err) }()
+	}
+	if h.metrics != nil {
+		started, requestBytes := time.Now(), readerLen(buf)
+		defer func() { h.observeRequest(ctx, operation, started, requestBytes, raw, response, err) }()
+	}
+
+	var class OperationClass
+	if h.limiter != nil {
+		class = operationClass(method, url.Path)
+		if err = h.limiter.Wait(ctx, class); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if authorize {
+		headers["Authorization"] = h.generateSasToken()
+	}
 	req, err := http.NewRequest(method, url.String(), buf)
 	if err != nil {
 		return nil, nil, err
 	}
-	req = req.WithContext(ctx)
+	if body, ok := buf.(*pooledBody); ok {
+		// http.NewRequest only sizes the standard readers
+		req.ContentLength, req.GetBody = body.size(), body.getBody
+	}
+	req = req.WithContext(utils.WithOperation(ctx, operation))
 	for header, val := range headers {
 		req.Header.Set(header, val)
 	}
-	return h.client.Exec(req)
+	// the logging is innermost to log the headers set by the other interceptors
+	interceptors := h.interceptors
+	if h.logging != nil {
+		interceptors = append(interceptors[:len(interceptors):len(interceptors)], h.logging)
+	}
+	raw, response, err = utils.Chain(h.client, interceptors...).Exec(req)
+	if h.limiter != nil && isThrottled(err) {
+		h.limiter.Throttle(class)
+	}
+	return raw, response, err
 }
 
 // generate an URL for path
@@ -4,9 +4,12 @@ import (
 	"context"
 	"net/http"
 	"net/url"
+	"reflect"
 	"testing"
+	"time"
 
 	. "github.com/daresaydigital/azure-notificationhubs-go"
+	"github.com/daresaydigital/azure-notificationhubs-go/utils"
 )
 
 func Test_NewNotificationHub(t *testing.T) {
@@ -71,3 +74,32 @@ func Test_HTTPRequestContext(t *testing.T) {
 	ctx := context.WithValue(context.Background(), "foo", "bar")
 	_, _, _ = nhub.Registrations(ctx)
 }
+
+func Test_Use(t *testing.T) {
+	var (
+		nhub, mockClient = initTestItems()
+		notification, _  = NewNotification(Template, []byte("{}"))
+		operations       []string
+	)
+	mockClient.execFunc = func(req *http.Request) ([]byte, *http.Response, error) {
+		if req.Header.Get(utils.RequestIDHeader) == "" {
+			t.Errorf(errfmt, "request ID header", "generated ID", "")
+		}
+		return nil, nil, nil
+	}
+
+	nhub.Use(func(next utils.HTTPClient) utils.HTTPClient {
+		return utils.HTTPClientFunc(func(req *http.Request) ([]byte, *http.Response, error) {
+			operations = append(operations, utils.OperationFromContext(req.Context()))
+			return next.Exec(req)
+		})
+	}, utils.RequestIDInterceptor(nil))
+
+	_, _, _ = nhub.Send(context.Background(), notification, nil)
+	_, _, _ = nhub.Schedule(context.Background(), notification, nil, time.Now().Add(time.Hour))
+	_ = nhub.Install(context.Background(), Installation{InstallationID: "installation-1"})
+
+	if expected := []string{"Send", "Schedule", "Install"}; !reflect.DeepEqual(operations, expected) {
+		t.Errorf(errfmt, "operations", expected, operations)
+	}
+}
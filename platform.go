@@ -34,11 +34,22 @@ func (f TargetPlatform) IsValid() bool {
 		f == AppleTemplatePlatform ||
 		f == BaiduPlatform ||
 		f == BaiduTemplatePlatform ||
+		f == BrowserPlatform ||
+		f == FcmV1Platform ||
 		f == GcmPlatform ||
 		f == GcmTemplatePlatform ||
 		f == TemplatePlatform ||
 		f == WindowsphonePlatform ||
 		f == WindowsphoneTemplatePlatform ||
 		f == WindowsPlatform ||
-		f == WindowsTemplatePlatform
+		f == WindowsTemplatePlatform ||
+		f == XiaomiPlatform
+}
+
+// IsTerminal identifies whether the notification will not change state anymore
+func (s NotificationState) IsTerminal() bool {
+	return s == Abandoned ||
+		s == Canceled ||
+		s == Completed ||
+		s == NoTargetFound
 }
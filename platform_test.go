@@ -99,3 +99,28 @@ func TestNotificationFormat_IsValid(t *testing.T) {
 		}
 	}
 }
+
+func TestNotificationState_IsTerminal(t *testing.T) {
+	var (
+		testCases = []struct {
+			state      NotificationState
+			isTerminal bool
+		}{
+			{state: Abandoned, isTerminal: true},
+			{state: Canceled, isTerminal: true},
+			{state: Completed, isTerminal: true},
+			{state: NoTargetFound, isTerminal: true},
+			{state: Enqueued, isTerminal: false},
+			{state: Processing, isTerminal: false},
+			{state: Scheduled, isTerminal: false},
+			{state: Unknown, isTerminal: false},
+		}
+	)
+
+	for _, testCase := range testCases {
+		obtained := testCase.state.IsTerminal()
+		if obtained != testCase.isTerminal {
+			t.Errorf("NotificationState '%s' IsTerminal(). Expected '%t', got '%t'", testCase.state, testCase.isTerminal, obtained)
+		}
+	}
+}
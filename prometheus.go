@@ -0,0 +1,204 @@
+package notificationhubs
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const prometheusContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// DefaultLatencyBuckets are the upper bounds in seconds of the request duration histogram
+var DefaultLatencyBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+type (
+	// MetricsCollector is a Metrics exposing the measurements in the Prometheus text exposition format
+	MetricsCollector struct {
+		buckets []float64
+
+		mu            sync.Mutex
+		requests      map[[2]string]float64
+		durations     map[string]*histogram
+		requestBytes  map[string]float64
+		responseBytes map[string]float64
+		retries       map[string]float64
+		throttles     map[string]float64
+		sends         map[string]float64
+	}
+
+	// histogram counts observations per bucket, counts are not cumulative
+	histogram struct {
+		counts []uint64
+		count  uint64
+		sum    float64
+	}
+)
+
+// NewMetricsCollector initializes and returns MetricsCollector pointer
+// Nil buckets are the DefaultLatencyBuckets
+func NewMetricsCollector(buckets []float64) *MetricsCollector {
+	if buckets == nil {
+		buckets = DefaultLatencyBuckets
+	}
+	buckets = append([]float64(nil), buckets...)
+	sort.Float64s(buckets)
+	return &MetricsCollector{
+		buckets:       buckets,
+		requests:      map[[2]string]float64{},
+		durations:     map[string]*histogram{},
+		requestBytes:  map[string]float64{},
+		responseBytes: map[string]float64{},
+		retries:       map[string]float64{},
+		throttles:     map[string]float64{},
+		sends:         map[string]float64{},
+	}
+}
+
+// ObserveRequest counts the request by status class and records its latency and payload sizes
+func (c *MetricsCollector) ObserveRequest(operation string, statusCode int, duration time.Duration, requestBytes, responseBytes int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.requests[[2]string{operation, statusClass(statusCode)}]++
+	h, ok := c.durations[operation]
+	if !ok {
+		h = &histogram{counts: make([]uint64, len(c.buckets))}
+		c.durations[operation] = h
+	}
+	seconds := duration.Seconds()
+	if i := sort.SearchFloat64s(c.buckets, seconds); i < len(c.buckets) {
+		h.counts[i]++
+	}
+	h.count++
+	h.sum += seconds
+	c.requestBytes[operation] += float64(requestBytes)
+	c.responseBytes[operation] += float64(responseBytes)
+}
+
+// ObserveRetry counts a retried request
+func (c *MetricsCollector) ObserveRetry(operation string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.retries[operation]++
+}
+
+// ObserveThrottle counts a throttled request
+func (c *MetricsCollector) ObserveThrottle(operation string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.throttles[operation]++
+}
+
+// ObserveSend counts a notification sent
+func (c *MetricsCollector) ObserveSend(format NotificationFormat) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sends[string(format)]++
+}
+
+// ServeHTTP writes the metrics in the Prometheus text exposition format
+func (c *MetricsCollector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", prometheusContentType)
+	_ = c.WriteText(w)
+}
+
+// WriteText writes the metrics in the Prometheus text exposition format
+func (c *MetricsCollector) WriteText(w io.Writer) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	b := bufio.NewWriter(w)
+	writeHeader(b, "notificationhubs_requests_total", "counter", "Hub requests by operation and status class.")
+	for _, key := range sortedPairs(c.requests) {
+		writeSample(b, "notificationhubs_requests_total", c.requests[key], "operation", key[0], "status_class", key[1])
+	}
+
+	writeHeader(b, "notificationhubs_request_duration_seconds", "histogram", "Hub request latency by operation.")
+	for _, operation := range sortedHistogramKeys(c.durations) {
+		h := c.durations[operation]
+		var cumulative uint64
+		for i, bound := range c.buckets {
+			cumulative += h.counts[i]
+			writeSample(b, "notificationhubs_request_duration_seconds_bucket", float64(cumulative), "operation", operation, "le", formatFloat(bound))
+		}
+		writeSample(b, "notificationhubs_request_duration_seconds_bucket", float64(h.count), "operation", operation, "le", "+Inf")
+		writeSample(b, "notificationhubs_request_duration_seconds_sum", h.sum, "operation", operation)
+		writeSample(b, "notificationhubs_request_duration_seconds_count", float64(h.count), "operation", operation)
+	}
+
+	writeCounter(b, "notificationhubs_request_bytes_total", "Hub request payload bytes by operation.", "operation", c.requestBytes)
+	writeCounter(b, "notificationhubs_response_bytes_total", "Hub response payload bytes by operation.", "operation", c.responseBytes)
+	writeCounter(b, "notificationhubs_retries_total", "Retried hub requests by operation.", "operation", c.retries)
+	writeCounter(b, "notificationhubs_throttled_total", "Throttled hub requests by operation.", "operation", c.throttles)
+	writeCounter(b, "notificationhubs_sends_total", "Notifications sent by format.", "format", c.sends)
+	return b.Flush()
+}
+
+// statusClass groups status codes as 2xx, 4xx, 5xx or error without a response
+func statusClass(statusCode int) string {
+	if statusCode == 0 {
+		return "error"
+	}
+	return strconv.Itoa(statusCode/100) + "xx"
+}
+
+func writeHeader(w io.Writer, name, kind, help string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+
+func writeCounter(w io.Writer, name, help, label string, values map[string]float64) {
+	writeHeader(w, name, "counter", help)
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		writeSample(w, name, values[key], label, key)
+	}
+}
+
+// writeSample writes a sample with label name and value pairs
+func writeSample(w io.Writer, name string, value float64, labels ...string) {
+	pairs := make([]string, 0, len(labels)/2)
+	for i := 0; i+1 < len(labels); i += 2 {
+		pairs = append(pairs, labels[i]+`="`+escapeLabelValue(labels[i+1])+`"`)
+	}
+	fmt.Fprintf(w, "%s{%s} %s\n", name, strings.Join(pairs, ","), formatFloat(value))
+}
+
+func escapeLabelValue(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
+
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+func sortedPairs(values map[[2]string]float64) [][2]string {
+	keys := make([][2]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i][0] == keys[j][0] {
+			return keys[i][1] < keys[j][1]
+		}
+		return keys[i][0] < keys[j][0]
+	})
+	return keys
+}
+
+func sortedHistogramKeys(values map[string]*histogram) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,80 @@
+package notificationhubs_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/daresaydigital/azure-notificationhubs-go"
+	"github.com/daresaydigital/azure-notificationhubs-go/utils"
+)
+
+func Test_MetricsCollector(t *testing.T) {
+	var (
+		nhub, mockClient = initTestItems()
+		collector        = NewMetricsCollector([]float64{0.1, 1})
+		notification, _  = NewNotification(Template, []byte(`{"a":"b"}`))
+		throttle         = false
+	)
+	nhub.SetMetrics(collector)
+
+	mockClient.execFunc = func(req *http.Request) ([]byte, *http.Response, error) {
+		if throttle {
+			return nil, nil, &utils.HTTPError{StatusCode: http.StatusTooManyRequests}
+		}
+		return []byte("ok"), &http.Response{
+			StatusCode: http.StatusCreated,
+			Header: http.Header{
+				"Location": []string{"https://testhub-ns.servicebus.windows.net/testhub/messages/1?api-version=2016-07"},
+			},
+		}, nil
+	}
+
+	_, _, _ = nhub.Send(context.Background(), notification, nil)
+	_, _, _ = nhub.Send(WithRetryAttempt(context.Background(), 2), notification, nil)
+	throttle = true
+	_, _, _ = nhub.Send(context.Background(), notification, nil)
+
+	recorder := httptest.NewRecorder()
+	collector.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if contentType := recorder.Header().Get("Content-Type"); !strings.HasPrefix(contentType, "text/plain; version=0.0.4") {
+		t.Errorf(errfmt, "Content-Type", "text/plain; version=0.0.4", contentType)
+	}
+
+	body := recorder.Body.String()
+	for _, line := range []string{
+		"# TYPE notificationhubs_requests_total counter",
+		`notificationhubs_requests_total{operation="Send",status_class="2xx"} 2`,
+		`notificationhubs_requests_total{operation="Send",status_class="4xx"} 1`,
+		"# TYPE notificationhubs_request_duration_seconds histogram",
+		`notificationhubs_request_duration_seconds_bucket{operation="Send",le="0.1"} 3`,
+		`notificationhubs_request_duration_seconds_bucket{operation="Send",le="+Inf"} 3`,
+		`notificationhubs_request_duration_seconds_count{operation="Send"} 3`,
+		`notificationhubs_request_bytes_total{operation="Send"} 27`,
+		`notificationhubs_response_bytes_total{operation="Send"} 4`,
+		`notificationhubs_retries_total{operation="Send"} 1`,
+		`notificationhubs_throttled_total{operation="Send"} 1`,
+		`notificationhubs_sends_total{format="template"} 2`,
+	} {
+		if !strings.Contains(body, line+"\n") {
+			t.Errorf(errfmt, "exposition line", line, body)
+		}
+	}
+}
+
+func TestMetricsCollectorEscapesLabels(t *testing.T) {
+	var (
+		collector = NewMetricsCollector(nil)
+		b         strings.Builder
+	)
+	collector.ObserveRequest("a\"b\\c", 0, time.Millisecond, 0, 0)
+	if err := collector.WriteText(&b); err != nil {
+		t.Fatal(err)
+	}
+	if want := `notificationhubs_requests_total{operation="a\"b\\c",status_class="error"} 1`; !strings.Contains(b.String(), want) {
+		t.Errorf(errfmt, "escaped labels", want, b.String())
+	}
+}
@@ -0,0 +1,265 @@
+package notificationhubs
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/daresaydigital/azure-notificationhubs-go/utils"
+)
+
+const (
+	defaultSlowdownFactor = 0.5
+	defaultMinRateFactor  = 0.05
+	defaultRecoveryPeriod = time.Minute
+)
+
+// SendOperation and the other operation classes limited by a RateLimiter
+const (
+	SendOperation              OperationClass = "send"
+	RegistrationWriteOperation OperationClass = "registrationWrite"
+	ReadOperation              OperationClass = "read"
+)
+
+type (
+	// OperationClass groups hub requests sharing a rate limit
+	OperationClass string
+
+	// RateLimit is a token bucket rate, Burst requests may be sent at once
+	RateLimit struct {
+		Rate  float64
+		Burst int
+	}
+
+	// RateLimiterOptions configures a RateLimiter
+	RateLimiterOptions struct {
+		// Limits are the rates of the operation classes, classes without a limit are not limited
+		Limits map[OperationClass]RateLimit
+		// SlowdownFactor multiplies the rate of a class on every throttled response, defaults to 0.5
+		SlowdownFactor float64
+		// MinRateFactor is the lowest fraction of the configured rate reached by slowing down, defaults to 0.05
+		MinRateFactor float64
+		// RecoveryPeriod is the time a slowed down rate takes to recover linearly, defaults to one minute
+		RecoveryPeriod time.Duration
+	}
+
+	// RateLimiterStats are the metrics of an operation class
+	RateLimiterStats struct {
+		// Rate is the current rate, lower than the configured rate while slowed down
+		Rate float64
+		// CurrentWait is the time a request sent now would wait
+		CurrentWait time.Duration
+		// Waits is the number of requests which waited
+		Waits int64
+		// TotalWait is the time waited by all requests
+		TotalWait time.Duration
+		// Throttled is the number of throttled responses
+		Throttled int64
+	}
+
+	// RateLimiter limits the hub requests per operation class with token buckets,
+	// slowing down when the hub throttles requests or their quota is exceeded
+	RateLimiter struct {
+		buckets map[OperationClass]*TokenBucket
+	}
+
+	// TokenBucket is a Limiter allowing Burst requests at once and Rate requests per second on average
+	TokenBucket struct {
+		limit          RateLimit
+		slowdown       float64
+		minFactor      float64
+		recoveryPeriod time.Duration
+
+		mu          sync.Mutex
+		tokens      float64
+		last        time.Time
+		factor      float64
+		throttledAt time.Time
+		stats       RateLimiterStats
+	}
+)
+
+// NewRateLimiter initializes and returns RateLimiter pointer
+func NewRateLimiter(opts *RateLimiterOptions) *RateLimiter {
+	var o RateLimiterOptions
+	if opts != nil {
+		o = *opts
+	}
+	if o.SlowdownFactor <= 0 || o.SlowdownFactor >= 1 {
+		o.SlowdownFactor = defaultSlowdownFactor
+	}
+	if o.MinRateFactor <= 0 || o.MinRateFactor > 1 {
+		o.MinRateFactor = defaultMinRateFactor
+	}
+	if o.RecoveryPeriod <= 0 {
+		o.RecoveryPeriod = defaultRecoveryPeriod
+	}
+
+	l := &RateLimiter{buckets: map[OperationClass]*TokenBucket{}}
+	for class, limit := range o.Limits {
+		bucket := NewTokenBucket(limit)
+		bucket.slowdown, bucket.minFactor, bucket.recoveryPeriod = o.SlowdownFactor, o.MinRateFactor, o.RecoveryPeriod
+		l.buckets[class] = bucket
+	}
+	return l
+}
+
+// SetRateLimiter makes every request of the hub wait on the limiter of its operation class
+func (h *NotificationHub) SetRateLimiter(l *RateLimiter) {
+	h.limiter = l
+}
+
+// Bucket returns the token bucket of an operation class, or nil if the class is not limited
+func (l *RateLimiter) Bucket(class OperationClass) *TokenBucket {
+	return l.buckets[class]
+}
+
+// Wait blocks until a request of the operation class may be sent
+func (l *RateLimiter) Wait(ctx context.Context, class OperationClass) error {
+	if bucket := l.buckets[class]; bucket != nil {
+		return bucket.Wait(ctx)
+	}
+	return nil
+}
+
+// Throttle slows down the operation class
+func (l *RateLimiter) Throttle(class OperationClass) {
+	if bucket := l.buckets[class]; bucket != nil {
+		bucket.Throttle()
+	}
+}
+
+// Stats returns the metrics of the limited operation classes
+func (l *RateLimiter) Stats() map[OperationClass]RateLimiterStats {
+	stats := make(map[OperationClass]RateLimiterStats, len(l.buckets))
+	for class, bucket := range l.buckets {
+		stats[class] = bucket.Stats()
+	}
+	return stats
+}
+
+// NewTokenBucket initializes and returns a full TokenBucket pointer
+func NewTokenBucket(limit RateLimit) *TokenBucket {
+	if limit.Burst < 1 {
+		limit.Burst = 1
+	}
+	return &TokenBucket{
+		limit:          limit,
+		slowdown:       defaultSlowdownFactor,
+		minFactor:      defaultMinRateFactor,
+		recoveryPeriod: defaultRecoveryPeriod,
+		tokens:         float64(limit.Burst),
+		last:           time.Now(),
+		factor:         1,
+	}
+}
+
+// Wait blocks until a token is available or the context is done
+// A bucket without a positive rate does not limit
+func (b *TokenBucket) Wait(ctx context.Context) error {
+	if b.limit.Rate <= 0 {
+		return nil
+	}
+	b.mu.Lock()
+	now := time.Now()
+	rate := b.refill(now)
+	b.tokens--
+	var wait time.Duration
+	if b.tokens < 0 {
+		wait = time.Duration(-b.tokens / rate * float64(time.Second))
+		b.stats.Waits++
+		b.stats.TotalWait += wait
+	}
+	b.mu.Unlock()
+
+	if wait == 0 {
+		return nil
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		b.mu.Lock()
+		b.tokens++
+		b.mu.Unlock()
+		return ctx.Err()
+	}
+}
+
+// Throttle slows down the rate, which recovers over the recovery period
+func (b *TokenBucket) Throttle() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := time.Now()
+	b.refill(now)
+	b.factor = b.factorAt(now) * b.slowdown
+	if b.factor < b.minFactor {
+		b.factor = b.minFactor
+	}
+	b.throttledAt = now
+	b.stats.Throttled++
+}
+
+// Stats returns the metrics of the bucket
+func (b *TokenBucket) Stats() RateLimiterStats {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	stats := b.stats
+	stats.Rate = b.refill(time.Now())
+	if b.tokens < 1 && stats.Rate > 0 {
+		stats.CurrentWait = time.Duration((1 - b.tokens) / stats.Rate * float64(time.Second))
+	}
+	return stats
+}
+
+// refill adds the tokens earned since the last refill and returns the current rate
+// The caller must hold the lock
+func (b *TokenBucket) refill(now time.Time) float64 {
+	rate := b.limit.Rate * b.factorAt(now)
+	b.tokens += rate * now.Sub(b.last).Seconds()
+	if burst := float64(b.limit.Burst); b.tokens > burst {
+		b.tokens = burst
+	}
+	b.last = now
+	return rate
+}
+
+// factorAt returns the fraction of the configured rate, recovering linearly after a throttle
+// The caller must hold the lock
+func (b *TokenBucket) factorAt(now time.Time) float64 {
+	if b.factor >= 1 {
+		return 1
+	}
+	recovered := float64(now.Sub(b.throttledAt)) / float64(b.recoveryPeriod)
+	if recovered >= 1 {
+		b.factor = 1
+		return 1
+	}
+	return b.factor + (1-b.factor)*recovered
+}
+
+// operationClass classifies a hub request for rate limiting
+func operationClass(method string, path string) OperationClass {
+	switch {
+	case method == postMethod && (strings.Contains(path, "/messages") || strings.Contains(path, "/schedulednotifications")):
+		return SendOperation
+	case method == getMethod || method == http.MethodHead:
+		return ReadOperation
+	}
+	return RegistrationWriteOperation
+}
+
+// isThrottled identifies throttled responses and exceeded quotas
+func isThrottled(err error) bool {
+	var httpErr *utils.HTTPError
+	if !errors.As(err, &httpErr) {
+		return false
+	}
+	return httpErr.StatusCode == http.StatusTooManyRequests ||
+		httpErr.StatusCode == http.StatusForbidden && strings.Contains(strings.ToLower(string(httpErr.Body)), "quota")
+}
@@ -0,0 +1,106 @@
+package notificationhubs_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	. "github.com/daresaydigital/azure-notificationhubs-go"
+	"github.com/daresaydigital/azure-notificationhubs-go/utils"
+)
+
+func TestTokenBucket(t *testing.T) {
+	var (
+		bucket = NewTokenBucket(RateLimit{Rate: 50, Burst: 2})
+		start  = time.Now()
+	)
+
+	for i := 0; i < 3; i++ {
+		if err := bucket.Wait(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 15*time.Millisecond {
+		t.Errorf(errfmt, "wait for third token", "about 20ms", elapsed)
+	}
+	stats := bucket.Stats()
+	if stats.Waits != 1 || stats.TotalWait <= 0 {
+		t.Errorf(errfmt, "Stats", "one wait", stats)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_ = bucket.Wait(context.Background())
+	if err := bucket.Wait(ctx); err != context.Canceled {
+		t.Errorf(errfmt, "Wait error", context.Canceled, err)
+	}
+}
+
+func TestTokenBucketThrottle(t *testing.T) {
+	bucket := NewRateLimiter(&RateLimiterOptions{
+		Limits:         map[OperationClass]RateLimit{SendOperation: {Rate: 100, Burst: 1}},
+		RecoveryPeriod: time.Hour,
+	}).Bucket(SendOperation)
+
+	bucket.Throttle()
+	if rate := bucket.Stats().Rate; rate < 49 || rate > 51 {
+		t.Errorf(errfmt, "throttled Rate", 50, rate)
+	}
+	for i := 0; i < 10; i++ {
+		bucket.Throttle()
+	}
+	if rate := bucket.Stats().Rate; rate < 4.9 || rate > 5.1 {
+		t.Errorf(errfmt, "minimum Rate", 5, rate)
+	}
+	if throttled := bucket.Stats().Throttled; throttled != 11 {
+		t.Errorf(errfmt, "Throttled", 11, throttled)
+	}
+}
+
+func Test_RateLimiterThrottledResponse(t *testing.T) {
+	var (
+		nhub, mockClient = initTestItems()
+		notification, _  = NewNotification(Template, []byte("{}"))
+		limiter          = NewRateLimiter(&RateLimiterOptions{
+			Limits: map[OperationClass]RateLimit{
+				SendOperation: {Rate: 1000, Burst: 10},
+				ReadOperation: {Rate: 1000, Burst: 10},
+			},
+		})
+	)
+	nhub.SetRateLimiter(limiter)
+
+	mockClient.execFunc = func(req *http.Request) ([]byte, *http.Response, error) {
+		if req.Method == getMethod {
+			return nil, nil, &utils.HTTPError{StatusCode: http.StatusForbidden, Body: []byte("Unauthorized")}
+		}
+		return nil, nil, &utils.HTTPError{StatusCode: http.StatusTooManyRequests}
+	}
+
+	_, _, _ = nhub.Send(context.Background(), notification, nil)
+	_, _, _ = nhub.Installation(context.Background(), "installation-1")
+
+	stats := limiter.Stats()
+	if stats[SendOperation].Throttled != 1 {
+		t.Errorf(errfmt, "send Throttled", 1, stats[SendOperation].Throttled)
+	}
+	if stats[ReadOperation].Throttled != 0 {
+		t.Errorf(errfmt, "read Throttled", 0, stats[ReadOperation].Throttled)
+	}
+	if _, ok := stats[RegistrationWriteOperation]; ok {
+		t.Errorf(errfmt, "registration write stats", "unlimited", stats[RegistrationWriteOperation])
+	}
+
+	// outcomes throttled by the push services do not slow down sends to the hub
+	mockClient.execFunc = func(req *http.Request) ([]byte, *http.Response, error) {
+		return []byte(`<NotificationDetails xmlns="http://schemas.microsoft.com/netservices/2010/10/servicebus/connect"><State>Completed</State><ApnsOutcomeCounts><Outcome><Name>ChannelThrottled</Name><Count>1</Count></Outcome></ApnsOutcomeCounts></NotificationDetails>`), nil, nil
+	}
+	details, _, err := nhub.NotificationDetails(context.Background(), "notification-1")
+	if err != nil || details.Count(ChannelThrottled) != 1 {
+		t.Fatalf(errfmt, "ChannelThrottled", 1, err)
+	}
+	if throttled := limiter.Stats()[SendOperation].Throttled; throttled != 1 {
+		t.Errorf(errfmt, "send Throttled after NotificationDetails", 1, throttled)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"context"
 	"encoding/xml"
 	"errors"
+	"net/url"
 	"path"
 	"strings"
 	"time"
@@ -83,11 +84,13 @@ func (r *RegistrationContent) normalize() {
 		r.GcmTemplateRegistrationDescription = nil
 	}
 	if r.RegisteredDevice != nil {
-		expirationTime, err := time.Parse("2006-01-02T15:04:05.000Z", *r.RegisteredDevice.ExpirationTimeString)
-		if err != nil { // The API just forwards the date string used by Apple, Google etc unfortunately. So format varies.
-			expirationTime, _ = time.Parse("2006-01-02T15:04:05.000", *r.RegisteredDevice.ExpirationTimeString)
+		if r.RegisteredDevice.ExpirationTimeString != nil {
+			expirationTime, err := time.Parse("2006-01-02T15:04:05.000Z", *r.RegisteredDevice.ExpirationTimeString)
+			if err != nil { // The API just forwards the date string used by Apple, Google etc unfortunately. So format varies.
+				expirationTime, _ = time.Parse("2006-01-02T15:04:05.000", *r.RegisteredDevice.ExpirationTimeString)
+			}
+			r.RegisteredDevice.ExpirationTime = &expirationTime
 		}
-		r.RegisteredDevice.ExpirationTime = &expirationTime
 		r.RegisteredDevice.ExpirationTimeString = nil
 		if r.RegisteredDevice.TagsString != nil {
 			r.RegisteredDevice.Tags = strings.Split(*r.RegisteredDevice.TagsString, ",")
@@ -101,7 +104,7 @@ func (h *NotificationHub) Registration(ctx context.Context, registrationID strin
 	var (
 		regURL = h.generateAPIURL(path.Join("registrations", registrationID))
 	)
-	raw, _, err = h.exec(ctx, getMethod, regURL, Headers{}, nil)
+	raw, _, err = h.exec(ctx, "Registration", getMethod, regURL, Headers{}, nil)
 	if err != nil {
 		return
 	}
@@ -112,9 +115,30 @@ func (h *NotificationHub) Registration(ctx context.Context, registrationID strin
 	return
 }
 
+// CreateRegistrationID reserves a registration ID in the hub
+// Registering with the reserved ID creates or replaces the registration, so the write can be retried
+func (h *NotificationHub) CreateRegistrationID(ctx context.Context) (string, error) {
+	_, response, err := h.exec(ctx, "CreateRegistrationID", postMethod, h.generateAPIURL("registrationIDs"), Headers{}, nil)
+	if err != nil {
+		return "", err
+	}
+	if response == nil {
+		return "", errors.New("no registration ID in the response")
+	}
+	location, err := url.Parse(response.Header.Get("Location"))
+	if err != nil {
+		return "", err
+	}
+	registrationID := path.Base(location.Path)
+	if registrationID == "" || registrationID == "." || registrationID == "/" {
+		return "", errors.New("no registration ID in the response")
+	}
+	return registrationID, nil
+}
+
 // Registrations reads all registrations
 func (h *NotificationHub) Registrations(ctx context.Context) (raw []byte, registrations *Registrations, err error) {
-	raw, _, err = h.exec(ctx, getMethod, h.generateAPIURL("registrations"), Headers{}, nil)
+	raw, _, err = h.exec(ctx, "Registrations", getMethod, h.generateAPIURL("registrations"), Headers{}, nil)
 	if err != nil {
 		return
 	}
@@ -125,6 +149,42 @@ func (h *NotificationHub) Registrations(ctx context.Context) (raw []byte, regist
 	return
 }
 
+// ForEachRegistration reads all registrations page by page and calls fn for every registration
+// Iteration stops at the first error returned by fn
+func (h *NotificationHub) ForEachRegistration(ctx context.Context, fn func(RegistrationResult) error) error {
+	continuationToken := ""
+	for {
+		regURL := h.generateAPIURL("registrations")
+		if continuationToken != "" {
+			query := regURL.Query()
+			query.Set(continuationTokenParam, continuationToken)
+			regURL.RawQuery = query.Encode()
+		}
+
+		raw, response, err := h.exec(ctx, "ForEachRegistration", getMethod, regURL, Headers{}, nil)
+		if err != nil {
+			return err
+		}
+		var registrations *Registrations
+		if err = xml.Unmarshal(raw, &registrations); err != nil {
+			return err
+		}
+		registrations.normalize()
+		for _, entry := range registrations.Entries {
+			if err = fn(entry); err != nil {
+				return err
+			}
+		}
+
+		if response == nil {
+			return nil
+		}
+		if continuationToken = response.Header.Get(continuationTokenHeader); continuationToken == "" {
+			return nil
+		}
+	}
+}
+
 // Register sends a device registration to the Azure hub
 func (h *NotificationHub) Register(ctx context.Context, r Registration) (raw []byte, registrationResult *RegistrationResult, err error) {
 	var (
@@ -151,7 +211,7 @@ func (h *NotificationHub) Register(ctx context.Context, r Registration) (raw []b
 		regURL.Path = path.Join(regURL.Path, r.RegistrationID)
 	}
 
-	raw, _, err = h.exec(ctx, method, regURL, headers, bytes.NewBufferString(payload))
+	raw, _, err = h.exec(ctx, "Register", method, regURL, headers, bytes.NewBufferString(payload))
 
 	if err == nil {
 		if err = xml.Unmarshal(raw, &registrationResult); err != nil {
@@ -191,7 +251,7 @@ func (h *NotificationHub) RegisterWithTemplate(ctx context.Context, r TemplateRe
 		regURL.Path = path.Join(regURL.Path, r.RegistrationID)
 	}
 
-	raw, _, err = h.exec(ctx, method, regURL, headers, bytes.NewBufferString(payload))
+	raw, _, err = h.exec(ctx, "RegisterWithTemplate", method, regURL, headers, bytes.NewBufferString(payload))
 
 	if err == nil {
 		if err = xml.Unmarshal(raw, &registrationResult); err != nil {
@@ -214,6 +274,6 @@ func (h *NotificationHub) Unregister(ctx context.Context, registration Registere
 		}
 	)
 
-	_, _, err = h.exec(ctx, deleteMethod, regURL, headers, nil)
+	_, _, err = h.exec(ctx, "Unregister", deleteMethod, regURL, headers, nil)
 	return
 }
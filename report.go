@@ -0,0 +1,220 @@
+package notificationhubs
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"sync"
+	"text/tabwriter"
+)
+
+type (
+	// TelemetryAggregatorOptions configures how a TelemetryAggregator collects the notification details
+	TelemetryAggregatorOptions struct {
+		// Label names the report, such as a day or a campaign
+		Label string
+		// Concurrency is the maximum number of requests in flight, defaults to 4
+		Concurrency int
+		// Limiter is waited on before every request, or nil to read as fast as possible
+		Limiter Limiter
+	}
+
+	// TelemetryAggregator sums the outcomes of many notifications
+	TelemetryAggregator struct {
+		hub  *NotificationHub
+		opts TelemetryAggregatorOptions
+
+		mu     sync.Mutex
+		report TelemetryReport
+	}
+
+	// TelemetryReport is the sum of the outcomes of many notifications
+	TelemetryReport struct {
+		Label         string                                             `json:"label,omitempty"`
+		Notifications int                                                `json:"notifications"`
+		States        map[NotificationState]int                          `json:"states"`
+		Outcomes      map[NotificationOutcomeName]int                    `json:"outcomes"`
+		Platforms     map[TargetPlatform]map[NotificationOutcomeName]int `json:"platforms"`
+		Failed        map[string]string                                  `json:"failed,omitempty"`
+	}
+)
+
+// NewTelemetryAggregator initializes and returns TelemetryAggregator pointer
+func NewTelemetryAggregator(hub *NotificationHub, opts *TelemetryAggregatorOptions) *TelemetryAggregator {
+	a := &TelemetryAggregator{
+		hub: hub,
+		report: TelemetryReport{
+			States:    map[NotificationState]int{},
+			Outcomes:  map[NotificationOutcomeName]int{},
+			Platforms: map[TargetPlatform]map[NotificationOutcomeName]int{},
+			Failed:    map[string]string{},
+		},
+	}
+	if opts != nil {
+		a.opts = *opts
+	}
+	a.report.Label = a.opts.Label
+	return a
+}
+
+// Collect reads the details of the notifications concurrently and adds them to the report
+// Notifications whose details can not be read are listed in the report's Failed map
+func (a *TelemetryAggregator) Collect(ctx context.Context, notificationIDs ...string) {
+	forEachParallel(len(notificationIDs), a.opts.Concurrency, func(i int) {
+		id := notificationIDs[i]
+		err := ctx.Err()
+		if err == nil && a.opts.Limiter != nil {
+			err = a.opts.Limiter.Wait(ctx)
+		}
+		var details *NotificationDetails
+		if err == nil {
+			details, _, err = a.hub.NotificationDetails(ctx, id)
+		}
+
+		a.mu.Lock()
+		defer a.mu.Unlock()
+		if err != nil {
+			a.report.Failed[id] = err.Error()
+			return
+		}
+		a.add(details)
+	})
+}
+
+// Add adds already read notification details to the report
+func (a *TelemetryAggregator) Add(details ...*NotificationDetails) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for _, d := range details {
+		a.add(d)
+	}
+}
+
+// add sums the details, a.mu must be held
+func (a *TelemetryAggregator) add(details *NotificationDetails) {
+	a.report.Notifications++
+	a.report.States[details.State]++
+	for platform, outcomes := range details.PlatformOutcomes() {
+		counts := a.report.Platforms[platform]
+		if counts == nil {
+			counts = map[NotificationOutcomeName]int{}
+			a.report.Platforms[platform] = counts
+		}
+		for _, outcome := range outcomes.Outcomes {
+			counts[outcome.Name] += outcome.Count
+			a.report.Outcomes[outcome.Name] += outcome.Count
+		}
+	}
+}
+
+// Report returns a copy of the report so far
+func (a *TelemetryAggregator) Report() *TelemetryReport {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	report := a.report
+	report.States = make(map[NotificationState]int, len(a.report.States))
+	for state, count := range a.report.States {
+		report.States[state] = count
+	}
+	report.Outcomes = make(map[NotificationOutcomeName]int, len(a.report.Outcomes))
+	for name, count := range a.report.Outcomes {
+		report.Outcomes[name] = count
+	}
+	report.Platforms = make(map[TargetPlatform]map[NotificationOutcomeName]int, len(a.report.Platforms))
+	for platform, outcomes := range a.report.Platforms {
+		report.Platforms[platform] = make(map[NotificationOutcomeName]int, len(outcomes))
+		for name, count := range outcomes {
+			report.Platforms[platform][name] = count
+		}
+	}
+	report.Failed = make(map[string]string, len(a.report.Failed))
+	for id, err := range a.report.Failed {
+		report.Failed[id] = err
+	}
+	return &report
+}
+
+// WriteJSON writes the report as indented JSON
+func (r *TelemetryReport) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(r)
+}
+
+// WriteCSV writes one label,platform,outcome,count row for every platform outcome
+// followed by the totals on rows with the platform "total"
+func (r *TelemetryReport) WriteCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{"label", "platform", "outcome", "count"}); err != nil {
+		return err
+	}
+	for _, platform := range r.platforms() {
+		for _, name := range sortedOutcomeNames(r.Platforms[platform]) {
+			count := strconv.Itoa(r.Platforms[platform][name])
+			if err := writer.Write([]string{r.Label, string(platform), string(name), count}); err != nil {
+				return err
+			}
+		}
+	}
+	for _, name := range sortedOutcomeNames(r.Outcomes) {
+		if err := writer.Write([]string{r.Label, "total", string(name), strconv.Itoa(r.Outcomes[name])}); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// WriteTable writes the report as a plain text table with one row per platform and one column per outcome
+func (r *TelemetryReport) WriteTable(w io.Writer) error {
+	if r.Label != "" {
+		if _, err := fmt.Fprintln(w, r.Label); err != nil {
+			return err
+		}
+	}
+
+	writer := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	names := sortedOutcomeNames(r.Outcomes)
+	fmt.Fprint(writer, "PLATFORM\t")
+	for _, name := range names {
+		fmt.Fprintf(writer, "%s\t", name)
+	}
+	fmt.Fprintln(writer)
+	for _, platform := range r.platforms() {
+		fmt.Fprintf(writer, "%s\t", platform)
+		for _, name := range names {
+			fmt.Fprintf(writer, "%d\t", r.Platforms[platform][name])
+		}
+		fmt.Fprintln(writer)
+	}
+	fmt.Fprint(writer, "TOTAL\t")
+	for _, name := range names {
+		fmt.Fprintf(writer, "%d\t", r.Outcomes[name])
+	}
+	fmt.Fprintln(writer)
+	return writer.Flush()
+}
+
+// platforms returns the platforms in the report, sorted
+func (r *TelemetryReport) platforms() []TargetPlatform {
+	platforms := make([]TargetPlatform, 0, len(r.Platforms))
+	for platform := range r.Platforms {
+		platforms = append(platforms, platform)
+	}
+	sort.Slice(platforms, func(i, j int) bool { return platforms[i] < platforms[j] })
+	return platforms
+}
+
+// sortedOutcomeNames returns the outcome names in counts, sorted
+func sortedOutcomeNames(counts map[NotificationOutcomeName]int) []NotificationOutcomeName {
+	names := make([]NotificationOutcomeName, 0, len(counts))
+	for name := range counts {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool { return names[i] < names[j] })
+	return names
+}
@@ -0,0 +1,95 @@
+package notificationhubs_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	. "github.com/daresaydigital/azure-notificationhubs-go"
+)
+
+func Test_TelemetryAggregator(t *testing.T) {
+	var (
+		nhub, mockClient = initTestItems()
+		limiter          = &mockLimiter{}
+		aggregator       = NewTelemetryAggregator(nhub, &TelemetryAggregatorOptions{Label: "2020-04-20", Concurrency: 2, Limiter: limiter})
+	)
+
+	mockClient.execFunc = func(req *http.Request) ([]byte, *http.Response, error) {
+		if strings.Contains(req.URL.Path, "/messages/missing") {
+			return nil, nil, errors.New("Got unexpected response status code: 404")
+		}
+		data, e := ioutil.ReadFile("./fixtures/notificationDetails.xml")
+		if e != nil {
+			return nil, nil, e
+		}
+		return data, nil, nil
+	}
+
+	aggregator.Collect(context.Background(), "1", "2", "missing")
+	report := aggregator.Report()
+
+	if report.Notifications != 2 {
+		t.Errorf(errfmt, "notifications", 2, report.Notifications)
+	}
+	if limiter.waits != 3 {
+		t.Errorf(errfmt, "limiter waits", 3, limiter.waits)
+	}
+	if _, ok := report.Failed["missing"]; !ok || len(report.Failed) != 1 {
+		t.Errorf(errfmt, "failed", "missing", report.Failed)
+	}
+	expectedOutcomes := map[NotificationOutcomeName]int{Success: 4, WrongToken: 2, ExpiredChannel: 2}
+	if !reflect.DeepEqual(report.Outcomes, expectedOutcomes) {
+		t.Errorf(errfmt, "outcomes", expectedOutcomes, report.Outcomes)
+	}
+	expectedPlatforms := map[TargetPlatform]map[NotificationOutcomeName]int{
+		ApplePlatform: {Success: 4, WrongToken: 2},
+		GcmPlatform:   {ExpiredChannel: 2},
+	}
+	if !reflect.DeepEqual(report.Platforms, expectedPlatforms) {
+		t.Errorf(errfmt, "platforms", expectedPlatforms, report.Platforms)
+	}
+	if report.States[Completed] != 2 {
+		t.Errorf(errfmt, "completed", 2, report.States[Completed])
+	}
+
+	var buf bytes.Buffer
+	if err := report.WriteCSV(&buf); err != nil {
+		t.Fatalf(errfmt, "CSV error", nil, err)
+	}
+	expectedCSV := `label,platform,outcome,count
+2020-04-20,apple,Success,4
+2020-04-20,apple,WrongToken,2
+2020-04-20,gcm,ExpiredChannel,2
+2020-04-20,total,ExpiredChannel,2
+2020-04-20,total,Success,4
+2020-04-20,total,WrongToken,2
+`
+	if buf.String() != expectedCSV {
+		t.Errorf(errfmt, "CSV", expectedCSV, buf.String())
+	}
+
+	buf.Reset()
+	if err := report.WriteTable(&buf); err != nil {
+		t.Fatalf(errfmt, "table error", nil, err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 5 || strings.Join(strings.Fields(lines[4]), " ") != "TOTAL 2 4 2" {
+		t.Errorf(errfmt, "table", "label, header, 2 platforms and totals", buf.String())
+	}
+
+	buf.Reset()
+	if err := report.WriteJSON(&buf); err != nil {
+		t.Fatalf(errfmt, "JSON error", nil, err)
+	}
+	var decoded TelemetryReport
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil || !reflect.DeepEqual(&decoded, report) {
+		t.Errorf(errfmt, "JSON round trip", report, decoded)
+	}
+}
@@ -0,0 +1,397 @@
+package notificationhubs
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const defaultRouterReplicas = 100
+
+type (
+	// HubRouterOptions configures a HubRouter
+	HubRouterOptions struct {
+		// Replicas is the number of points of every hub on the consistent hash ring, defaults to 100
+		Replicas int
+		// Migrate moves the devices of a tenant between hubs when it is rebalanced, or nil
+		Migrate func(ctx context.Context, tenant string, from, to *NotificationHub) error
+		// Assignments keeps the tenants assigned to a hub, share a persistent store between processes
+		// to route the assigned tenants alike, defaults to a MemoryTenantAssignmentStore which is lost when the process exits
+		Assignments TenantAssignmentStore
+	}
+
+	// TenantAssignmentStore maps tenants to the names of the hubs they are assigned to
+	TenantAssignmentStore interface {
+		HubName(tenant string) (hubName string, ok bool, err error)
+		SetHubName(tenant, hubName string) error
+		Assignments() (map[string]string, error)
+	}
+
+	// MemoryTenantAssignmentStore is a TenantAssignmentStore kept in memory
+	MemoryTenantAssignmentStore struct {
+		mu          sync.RWMutex
+		assignments map[string]string
+	}
+
+	// HubRouter maps tenants to named hubs, from the assignment store
+	// or else by consistent hashing of the tenant key
+	// Consistent hashing only depends on the set of hubs, so routers with the same hubs agree.
+	// Adding a hub moves about one in N of the tenants without an assignment to the new hub,
+	// Pin or Rebalance the existing tenants before adding a hub
+	HubRouter struct {
+		opts HubRouterOptions
+
+		mu   sync.RWMutex
+		hubs map[string]*NotificationHub
+		ring []routerPoint
+
+		locksMu sync.Mutex
+		locks   map[string]*tenantLock
+	}
+
+	// tenantLock blocks the writes of a tenant while it is rebalanced
+	tenantLock struct {
+		sync.RWMutex
+		refs int
+	}
+
+	// routerPoint is a point of a hub on the consistent hash ring
+	routerPoint struct {
+		hash uint32
+		name string
+	}
+)
+
+// NewHubRouter initializes and returns HubRouter pointer routing to the named hubs
+func NewHubRouter(hubs map[string]*NotificationHub, opts *HubRouterOptions) *HubRouter {
+	r := &HubRouter{
+		hubs:  map[string]*NotificationHub{},
+		locks: map[string]*tenantLock{},
+	}
+	if opts != nil {
+		r.opts = *opts
+	}
+	if r.opts.Replicas < 1 {
+		r.opts.Replicas = defaultRouterReplicas
+	}
+	if r.opts.Assignments == nil {
+		r.opts.Assignments = NewMemoryTenantAssignmentStore()
+	}
+	for name, hub := range hubs {
+		r.hubs[name] = hub
+	}
+	r.buildRing()
+	return r
+}
+
+// AddHub adds a named hub to the ring
+// The tenants without an assignment whose ring point now falls to the new hub are routed to it
+// without migration, call Pin with the existing tenants first to keep them on their hub
+func (r *HubRouter) AddHub(name string, hub *NotificationHub) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.hubs[name] = hub
+	r.buildRing()
+}
+
+// Assign routes a tenant to a named hub, overriding the consistent hashing
+func (r *HubRouter) Assign(tenant, hubName string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.hubs[hubName]; !ok {
+		return fmt.Errorf("unknown hub %q", hubName)
+	}
+	return r.opts.Assignments.SetHubName(tenant, hubName)
+}
+
+// Pin assigns tenants without an assignment to the hub consistent hashing currently routes them to
+func (r *HubRouter) Pin(tenants ...string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, tenant := range tenants {
+		_, ok, err := r.opts.Assignments.HubName(tenant)
+		if err != nil {
+			return err
+		}
+		if ok {
+			continue
+		}
+		name, err := r.ringHubName(tenant)
+		if err != nil {
+			return err
+		}
+		if err = r.opts.Assignments.SetHubName(tenant, name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Assignments returns the tenants assigned to a hub and the names of their hubs
+func (r *HubRouter) Assignments() (map[string]string, error) {
+	return r.opts.Assignments.Assignments()
+}
+
+// HubName returns the name of the hub serving a tenant
+func (r *HubRouter) HubName(tenant string) (string, error) {
+	name, _, err := r.route(tenant)
+	return name, err
+}
+
+// Hub returns the hub serving a tenant
+func (r *HubRouter) Hub(tenant string) (*NotificationHub, error) {
+	_, hub, err := r.route(tenant)
+	return hub, err
+}
+
+// Rebalance moves a tenant to a named hub, calling the migration hook before the tenant is routed to it
+// The tenant is not moved if the migration fails. Installation and registration writes of the tenant
+// wait until the migration finished and then go to the hub serving the tenant, sends and reads are
+// served by the previous hub in the meantime
+func (r *HubRouter) Rebalance(ctx context.Context, tenant, hubName string) error {
+	lock := r.lockTenant(tenant)
+	lock.Lock()
+	defer r.unlockTenant(tenant, lock, lock.Unlock)
+
+	from, fromHub, err := r.route(tenant)
+	if err != nil {
+		return err
+	}
+	r.mu.RLock()
+	to, ok := r.hubs[hubName]
+	r.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("unknown hub %q", hubName)
+	}
+	if from == hubName {
+		return r.Assign(tenant, hubName)
+	}
+
+	if r.opts.Migrate != nil {
+		if err = r.opts.Migrate(ctx, tenant, fromHub, to); err != nil {
+			return fmt.Errorf("migrating tenant %q from %q to %q: %w", tenant, from, hubName, err)
+		}
+	}
+	return r.Assign(tenant, hubName)
+}
+
+// Send publishes a notification through the hub of the tenant
+func (r *HubRouter) Send(ctx context.Context, tenant string, n *Notification, tags *string) ([]byte, *NotificationTelemetry, error) {
+	hub, err := r.Hub(tenant)
+	if err != nil {
+		return nil, nil, err
+	}
+	return hub.Send(ctx, n, tags)
+}
+
+// SendDirect publishes a notification directly to a device through the hub of the tenant
+func (r *HubRouter) SendDirect(ctx context.Context, tenant string, n *Notification, deviceHandle string) ([]byte, *NotificationTelemetry, error) {
+	hub, err := r.Hub(tenant)
+	if err != nil {
+		return nil, nil, err
+	}
+	return hub.SendDirect(ctx, n, deviceHandle)
+}
+
+// Schedule schedules a notification through the hub of the tenant
+func (r *HubRouter) Schedule(ctx context.Context, tenant string, n *Notification, tags *string, deliverTime time.Time) ([]byte, *NotificationTelemetry, error) {
+	hub, err := r.Hub(tenant)
+	if err != nil {
+		return nil, nil, err
+	}
+	return hub.Schedule(ctx, n, tags, deliverTime)
+}
+
+// Installation reads an installation from the hub of the tenant
+func (r *HubRouter) Installation(ctx context.Context, tenant, installationID string) ([]byte, *Installation, error) {
+	hub, err := r.Hub(tenant)
+	if err != nil {
+		return nil, nil, err
+	}
+	return hub.Installation(ctx, installationID)
+}
+
+// Install writes an installation to the hub of the tenant
+func (r *HubRouter) Install(ctx context.Context, tenant string, installation Installation) error {
+	return r.write(tenant, func(hub *NotificationHub) error {
+		return hub.Install(ctx, installation)
+	})
+}
+
+// Update applies installation changes on the hub of the tenant
+func (r *HubRouter) Update(ctx context.Context, tenant, installationID string, changes ...InstallationChange) error {
+	return r.write(tenant, func(hub *NotificationHub) error {
+		return hub.Update(ctx, installationID, changes...)
+	})
+}
+
+// Uninstall removes an installation from the hub of the tenant
+func (r *HubRouter) Uninstall(ctx context.Context, tenant, installationID string) error {
+	return r.write(tenant, func(hub *NotificationHub) error {
+		return hub.Uninstall(ctx, installationID)
+	})
+}
+
+// Registration reads a registration from the hub of the tenant
+func (r *HubRouter) Registration(ctx context.Context, tenant, registrationID string) ([]byte, *RegistrationResult, error) {
+	hub, err := r.Hub(tenant)
+	if err != nil {
+		return nil, nil, err
+	}
+	return hub.Registration(ctx, registrationID)
+}
+
+// Register writes a registration to the hub of the tenant
+func (r *HubRouter) Register(ctx context.Context, tenant string, registration Registration) (raw []byte, result *RegistrationResult, err error) {
+	err = r.write(tenant, func(hub *NotificationHub) (e error) {
+		raw, result, e = hub.Register(ctx, registration)
+		return
+	})
+	return
+}
+
+// RegisterWithTemplate writes a template registration to the hub of the tenant
+func (r *HubRouter) RegisterWithTemplate(ctx context.Context, tenant string, registration TemplateRegistration) (raw []byte, result *RegistrationResult, err error) {
+	err = r.write(tenant, func(hub *NotificationHub) (e error) {
+		raw, result, e = hub.RegisterWithTemplate(ctx, registration)
+		return
+	})
+	return
+}
+
+// Unregister removes a registration from the hub of the tenant
+func (r *HubRouter) Unregister(ctx context.Context, tenant string, registration RegisteredDevice) error {
+	return r.write(tenant, func(hub *NotificationHub) error {
+		return hub.Unregister(ctx, registration)
+	})
+}
+
+// write calls fn with the hub of the tenant once a rebalance of the tenant finished
+func (r *HubRouter) write(tenant string, fn func(hub *NotificationHub) error) error {
+	lock := r.lockTenant(tenant)
+	lock.RLock()
+	defer r.unlockTenant(tenant, lock, lock.RUnlock)
+
+	hub, err := r.Hub(tenant)
+	if err != nil {
+		return err
+	}
+	return fn(hub)
+}
+
+// lockTenant returns the lock of a tenant, which must be released with unlockTenant
+func (r *HubRouter) lockTenant(tenant string) *tenantLock {
+	r.locksMu.Lock()
+	defer r.locksMu.Unlock()
+	lock, ok := r.locks[tenant]
+	if !ok {
+		lock = &tenantLock{}
+		r.locks[tenant] = lock
+	}
+	lock.refs++
+	return lock
+}
+
+// unlockTenant calls unlock and forgets the lock of a tenant once it is no longer used
+func (r *HubRouter) unlockTenant(tenant string, lock *tenantLock, unlock func()) {
+	unlock()
+	r.locksMu.Lock()
+	defer r.locksMu.Unlock()
+	if lock.refs--; lock.refs == 0 {
+		delete(r.locks, tenant)
+	}
+}
+
+// route returns the hub serving a tenant, from its assignment or else its point on the ring
+func (r *HubRouter) route(tenant string) (string, *NotificationHub, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	name, ok, err := r.opts.Assignments.HubName(tenant)
+	if err != nil {
+		return "", nil, err
+	}
+	if !ok {
+		if name, err = r.ringHubName(tenant); err != nil {
+			return "", nil, err
+		}
+	}
+	hub, ok := r.hubs[name]
+	if !ok {
+		return "", nil, fmt.Errorf("tenant %q is assigned to unknown hub %q", tenant, name)
+	}
+	return name, hub, nil
+}
+
+// ringHubName looks up the point of a tenant on the ring
+// The caller must hold the lock
+func (r *HubRouter) ringHubName(tenant string) (string, error) {
+	if len(r.ring) == 0 {
+		return "", fmt.Errorf("no hub for tenant %q", tenant)
+	}
+	hash := routerHash(tenant)
+	i := sort.Search(len(r.ring), func(i int) bool { return r.ring[i].hash >= hash })
+	if i == len(r.ring) {
+		i = 0
+	}
+	return r.ring[i].name, nil
+}
+
+// buildRing places the replicas of every hub on the ring
+// The caller must hold the lock
+func (r *HubRouter) buildRing() {
+	r.ring = r.ring[:0]
+	for name := range r.hubs {
+		for i := 0; i < r.opts.Replicas; i++ {
+			r.ring = append(r.ring, routerPoint{hash: routerHash(name + "#" + strconv.Itoa(i)), name: name})
+		}
+	}
+	sort.Slice(r.ring, func(i, j int) bool {
+		if r.ring[i].hash == r.ring[j].hash {
+			return r.ring[i].name < r.ring[j].name
+		}
+		return r.ring[i].hash < r.ring[j].hash
+	})
+}
+
+// routerHash hashes a key onto the ring
+func routerHash(key string) uint32 {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(key))
+	return h.Sum32()
+}
+
+// NewMemoryTenantAssignmentStore returns an empty MemoryTenantAssignmentStore
+func NewMemoryTenantAssignmentStore() *MemoryTenantAssignmentStore {
+	return &MemoryTenantAssignmentStore{assignments: map[string]string{}}
+}
+
+// HubName returns the name of the hub a tenant is assigned to
+func (s *MemoryTenantAssignmentStore) HubName(tenant string) (string, bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	name, ok := s.assignments[tenant]
+	return name, ok, nil
+}
+
+// SetHubName assigns a tenant to a named hub
+func (s *MemoryTenantAssignmentStore) SetHubName(tenant, hubName string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.assignments[tenant] = hubName
+	return nil
+}
+
+// Assignments returns a copy of the assignments
+func (s *MemoryTenantAssignmentStore) Assignments() (map[string]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	assignments := make(map[string]string, len(s.assignments))
+	for tenant, name := range s.assignments {
+		assignments[tenant] = name
+	}
+	return assignments, nil
+}
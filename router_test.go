@@ -0,0 +1,232 @@
+package notificationhubs_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/daresaydigital/azure-notificationhubs-go"
+	"github.com/daresaydigital/azure-notificationhubs-go/utils"
+)
+
+func initRouterTestItems(names ...string) (map[string]*NotificationHub, map[string]int) {
+	var (
+		hubs     = map[string]*NotificationHub{}
+		requests = map[string]int{}
+	)
+	for _, name := range names {
+		name := name
+		nhub, mockClient := initTestItems()
+		mockClient.execFunc = func(req *http.Request) ([]byte, *http.Response, error) {
+			requests[name]++
+			return nil, nil, nil
+		}
+		hubs[name] = nhub
+	}
+	return hubs, requests
+}
+
+func TestHubRouterConsistentHashing(t *testing.T) {
+	var (
+		hubs, _ = initRouterTestItems("hub-a", "hub-b", "hub-c")
+		router  = NewHubRouter(hubs, nil)
+		counts  = map[string]int{}
+		before  = map[string]string{}
+	)
+
+	for i := 0; i < 3000; i++ {
+		tenant := fmt.Sprintf("tenant-%d", i)
+		name, err := router.HubName(tenant)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if again, _ := router.HubName(tenant); again != name {
+			t.Errorf(errfmt, "stable HubName", name, again)
+		}
+		counts[name]++
+		before[tenant] = name
+	}
+	for name, count := range counts {
+		if count < 500 {
+			t.Errorf(errfmt, "tenants on "+name, "at least 500", count)
+		}
+	}
+
+	// a router over the same hubs agrees without any shared state
+	if name, _ := NewHubRouter(hubs, nil).HubName("tenant-42"); name != before["tenant-42"] {
+		t.Errorf(errfmt, "HubName of another router", before["tenant-42"], name)
+	}
+
+	var pinned []string
+	for i := 0; i < 100; i++ {
+		pinned = append(pinned, fmt.Sprintf("tenant-%d", i))
+	}
+	if err := router.Pin(pinned...); err != nil {
+		t.Fatal(err)
+	}
+	if assignments, _ := router.Assignments(); len(assignments) != len(pinned) || assignments["tenant-42"] != before["tenant-42"] {
+		t.Errorf(errfmt, "Assignments", len(pinned), assignments)
+	}
+
+	extra, _ := initRouterTestItems("hub-d")
+	router.AddHub("hub-d", extra["hub-d"])
+	counts = map[string]int{}
+	for _, tenant := range pinned {
+		if name, _ := router.HubName(tenant); name != before[tenant] {
+			t.Errorf(errfmt, "pinned tenant "+tenant, before[tenant], name)
+		}
+	}
+	for i := 100; i < 3000; i++ {
+		tenant := fmt.Sprintf("tenant-%d", i)
+		name, _ := router.HubName(tenant)
+		if name != before[tenant] && name != "hub-d" {
+			t.Errorf(errfmt, "moved tenant "+tenant, "hub-d", name)
+		}
+		counts[name]++
+	}
+	if counts["hub-d"] < 400 {
+		t.Errorf(errfmt, "tenants moved to hub-d", "at least 400", counts["hub-d"])
+	}
+}
+
+func TestHubRouterSharedAssignments(t *testing.T) {
+	var (
+		hubs, _ = initRouterTestItems("hub-a", "hub-b")
+		store   = NewMemoryTenantAssignmentStore()
+		router  = NewHubRouter(hubs, &HubRouterOptions{Assignments: store})
+		replica = NewHubRouter(hubs, &HubRouterOptions{Assignments: store})
+	)
+
+	name, _ := router.HubName("tenant")
+	other := "hub-a"
+	if name == other {
+		other = "hub-b"
+	}
+	if err := router.Assign("tenant", other); err != nil {
+		t.Fatal(err)
+	}
+	if name, _ = replica.HubName("tenant"); name != other {
+		t.Errorf(errfmt, "HubName of the replica", other, name)
+	}
+
+	if err := store.SetHubName("tenant", "hub-x"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := replica.HubName("tenant"); err == nil {
+		t.Errorf(errfmt, "HubName error", "unknown hub", err)
+	}
+}
+
+func Test_HubRouterRebalance(t *testing.T) {
+	var (
+		hubs, requests  = initRouterTestItems("hub-a", "hub-b")
+		migrations      []string
+		migrateErr      error
+		notification, _ = NewNotification(Template, []byte("{}"))
+		router          = NewHubRouter(hubs, &HubRouterOptions{
+			Migrate: func(ctx context.Context, tenant string, from, to *NotificationHub) error {
+				if from == hubs["hub-a"] && to == hubs["hub-b"] {
+					migrations = append(migrations, tenant+": hub-a -> hub-b")
+				}
+				return migrateErr
+			},
+		})
+	)
+
+	if err := router.Assign("tenant", "hub-a"); err != nil {
+		t.Fatal(err)
+	}
+	if err := router.Assign("tenant", "hub-x"); err == nil {
+		t.Errorf(errfmt, "Assign unknown hub error", "unknown hub", err)
+	}
+	_ = router.Install(context.Background(), "tenant", Installation{InstallationID: "installation-1"})
+	if requests["hub-a"] != 1 {
+		t.Errorf(errfmt, "hub-a requests", 1, requests["hub-a"])
+	}
+
+	migrateErr = errors.New("test error")
+	if err := router.Rebalance(context.Background(), "tenant", "hub-b"); err == nil || !errors.Is(err, migrateErr) {
+		t.Errorf(errfmt, "Rebalance error", migrateErr, err)
+	}
+	if name, _ := router.HubName("tenant"); name != "hub-a" {
+		t.Errorf(errfmt, "HubName after failed migration", "hub-a", name)
+	}
+
+	migrateErr = nil
+	if err := router.Rebalance(context.Background(), "tenant", "hub-b"); err != nil {
+		t.Fatal(err)
+	}
+	if name, _ := router.HubName("tenant"); name != "hub-b" {
+		t.Errorf(errfmt, "HubName after migration", "hub-b", name)
+	}
+	if len(migrations) != 2 {
+		t.Errorf(errfmt, "migrations", 2, migrations)
+	}
+
+	_, _, _ = router.Send(context.Background(), "tenant", notification, nil)
+	if requests["hub-b"] != 1 {
+		t.Errorf(errfmt, "hub-b requests", 1, requests["hub-b"])
+	}
+}
+
+func Test_HubRouterRebalanceBlocksWrites(t *testing.T) {
+	var (
+		hubs, _   = initRouterTestItems("hub-a", "hub-b")
+		migrating = make(chan struct{})
+		release   = make(chan struct{})
+		router    = NewHubRouter(hubs, &HubRouterOptions{
+			Migrate: func(ctx context.Context, tenant string, from, to *NotificationHub) error {
+				close(migrating)
+				<-release
+				return nil
+			},
+		})
+		mu       sync.Mutex
+		requests = map[*NotificationHub]int{}
+	)
+	for _, name := range []string{"hub-a", "hub-b"} {
+		hub := hubs[name]
+		hub.Use(func(next utils.HTTPClient) utils.HTTPClient {
+			return utils.HTTPClientFunc(func(req *http.Request) ([]byte, *http.Response, error) {
+				mu.Lock()
+				requests[hub]++
+				mu.Unlock()
+				return nil, nil, nil
+			})
+		})
+	}
+	if err := router.Assign("tenant", "hub-a"); err != nil {
+		t.Fatal(err)
+	}
+
+	rebalanced := make(chan error)
+	go func() { rebalanced <- router.Rebalance(context.Background(), "tenant", "hub-b") }()
+	<-migrating
+
+	installed := make(chan error)
+	go func() {
+		installed <- router.Install(context.Background(), "tenant", Installation{InstallationID: "installation-1"})
+	}()
+	select {
+	case <-installed:
+		t.Fatal("Install did not wait for the rebalance")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	close(release)
+	if err := <-rebalanced; err != nil {
+		t.Fatal(err)
+	}
+	if err := <-installed; err != nil {
+		t.Fatal(err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if requests[hubs["hub-a"]] != 0 || requests[hubs["hub-b"]] != 1 {
+		t.Errorf(errfmt, "requests", "1 on hub-b", requests)
+	}
+}
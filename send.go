@@ -7,14 +7,34 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"mime/multipart"
+	"net/http"
 	"net/textproto"
 	"net/url"
 	"path"
 	"strconv"
+	"sync"
 	"time"
 )
 
+// maxPooledBatchBufferSize is the largest batch body buffer kept for reuse
+const maxPooledBatchBufferSize = 1 << 20
+
+// batchBufferPool holds the buffers of the batch bodies
+var batchBufferPool = sync.Pool{New: func() interface{} { return new(bytes.Buffer) }}
+
+// errBodyClosed is returned by a read of a pooled request body after the transport closed it
+var errBodyClosed = errors.New("notificationhubs: read on closed request body")
+
+// pooledBody is a batch request body returning its buffer to the pool once the transport closed it
+// RoundTrip may read and close the body after it returned, so the buffer is not reused before
+type pooledBody struct {
+	mu     sync.Mutex
+	buf    *bytes.Buffer
+	reader *bytes.Reader
+}
+
 // Send publishes notification directly
 // Format tags according to https://docs.microsoft.com/en-us/azure/notification-hubs/notification-hubs-tags-segment-push-message
 // ex. "(follows_RedSox || follows_Cardinals) && location_Boston"
@@ -22,7 +42,7 @@ import (
 func (h *NotificationHub) Send(ctx context.Context, n *Notification, tags *string) (raw []byte, telemetry *NotificationTelemetry, err error) {
 	raw, telemetry, err = h.send(ctx, n, tags, nil)
 	if err != nil {
-		return nil, nil, fmt.Errorf("notificationhubs.SendDirect: %s", err)
+		return nil, nil, fmt.Errorf("notificationhubs.SendDirect: %w", err)
 	}
 	return
 }
@@ -31,7 +51,7 @@ func (h *NotificationHub) Send(ctx context.Context, n *Notification, tags *strin
 func (h *NotificationHub) SendDirect(ctx context.Context, n *Notification, deviceHandle string) (raw []byte, telemetry *NotificationTelemetry, err error) {
 	raw, telemetry, err = h.sendDirect(ctx, n, deviceHandle)
 	if err != nil {
-		return nil, nil, fmt.Errorf("notificationhubs.SendDirect: %s", err)
+		return nil, nil, fmt.Errorf("notificationhubs.SendDirect: %w", err)
 	}
 	return
 }
@@ -40,18 +60,56 @@ func (h *NotificationHub) SendDirect(ctx context.Context, n *Notification, devic
 func (h *NotificationHub) SendDirectBatch(ctx context.Context, n *Notification, deviceHandles ...string) (raw []byte, telemetry *NotificationTelemetry, err error) {
 	raw, telemetry, err = h.sendDirectBatch(ctx, n, deviceHandles)
 	if err != nil {
-		return nil, nil, fmt.Errorf("notificationhubs.SendDirectBatch: %s", err)
+		return nil, nil, fmt.Errorf("notificationhubs.SendDirectBatch: %w", err)
+	}
+	return
+}
+
+// SendToUser publishes notification to every installation of the given users
+// The user IDs are split across several requests when needed to stay within the hub's tag expression limits
+func (h *NotificationHub) SendToUser(ctx context.Context, n *Notification, userIDs ...string) (telemetry []*NotificationTelemetry, err error) {
+	tags := make([]string, len(userIDs))
+	for i, userID := range userIDs {
+		tags[i] = UserIDTag(userID)
+	}
+	telemetry, err = h.sendToTagsTelemetry(ctx, n, tags)
+	if err != nil {
+		return telemetry, fmt.Errorf("notificationhubs.SendToUser: %w", err)
+	}
+	return
+}
+
+// SendToInstallations publishes notification to the given installations
+// The installation IDs are split across several requests when needed to stay within the hub's tag expression limits
+func (h *NotificationHub) SendToInstallations(ctx context.Context, n *Notification, installationIDs ...string) (telemetry []*NotificationTelemetry, err error) {
+	tags := make([]string, len(installationIDs))
+	for i, installationID := range installationIDs {
+		tags[i] = InstallationIDTag(installationID)
+	}
+	telemetry, err = h.sendToTagsTelemetry(ctx, n, tags)
+	if err != nil {
+		return telemetry, fmt.Errorf("notificationhubs.SendToInstallations: %w", err)
 	}
 	return
 }
 
+// UserIDTag returns the reserved tag the hub adds to installations with the given user ID
+func UserIDTag(userID string) string {
+	return userIDTagPrefix + "{" + userID + "}"
+}
+
+// InstallationIDTag returns the reserved tag the hub adds to the installation with the given ID
+func InstallationIDTag(installationID string) string {
+	return installationIDTagPrefix + "{" + installationID + "}"
+}
+
 // Schedule publishes a scheduled notification
 // Format tags according to https://docs.microsoft.com/en-us/azure/notification-hubs/notification-hubs-tags-segment-push-message
 // or nil if no tags should be used
 func (h *NotificationHub) Schedule(ctx context.Context, n *Notification, tags *string, deliverTime time.Time) (raw []byte, telemetry *NotificationTelemetry, err error) {
 	raw, telemetry, err = h.send(ctx, n, tags, &deliverTime)
 	if err != nil {
-		return nil, nil, fmt.Errorf("notificationhubs.Schedule: %s", err)
+		return nil, nil, fmt.Errorf("notificationhubs.Schedule: %w", err)
 	}
 	return
 }
@@ -64,7 +122,8 @@ func (h *NotificationHub) send(ctx context.Context, n *Notification, tags *strin
 			"ServiceBusNotification-Format": string(n.Format),
 			"X-Apns-Expiration":             strconv.FormatInt(h.expirationTimeGenerator.GenerateTimestamp(), 10), //apns-expiration
 		}
-		_url = h.generateAPIURL("")
+		_url      = h.generateAPIURL("")
+		operation = "Send"
 	)
 
 	if tags != nil && len(*tags) > 0 {
@@ -85,6 +144,7 @@ func (h *NotificationHub) send(ctx context.Context, n *Notification, tags *strin
 	if deliverTime != nil {
 		if deliverTime.After(time.Now()) {
 			_url.Path = path.Join(_url.Path, "schedulednotifications")
+			operation = "Schedule"
 			headers["ServiceBusNotification-ScheduleTime"] = deliverTime.Format("2006-01-02T15:04:05")
 		} else {
 			return nil, nil, errors.New("you can not schedule a notification in the past")
@@ -93,10 +153,11 @@ func (h *NotificationHub) send(ctx context.Context, n *Notification, tags *strin
 		_url.Path = path.Join(_url.Path, "messages")
 	}
 
-	raw, response, err := h.exec(ctx, postMethod, _url, headers, bytes.NewBuffer(n.Payload))
+	raw, response, err := h.exec(ctx, operation, postMethod, _url, headers, bytes.NewBuffer(n.Payload))
 	if err != nil {
 		return
 	}
+	h.observeSend(n.Format)
 	telemetry, err = NewNotificationTelemetryFromHTTPResponse(response)
 	return
 }
@@ -107,7 +168,7 @@ func (h *NotificationHub) sendDirect(ctx context.Context, n *Notification, devic
 			"Content-Type":                        n.Format.GetContentType(),
 			"ServiceBusNotification-Format":       string(n.Format),
 			"ServiceBusNotification-DeviceHandle": deviceHandle,
-			"X-Apns-Expiration":                   string(h.expirationTimeGenerator.GenerateTimestamp()), //apns-expiration
+			"X-Apns-Expiration":                   strconv.FormatInt(h.expirationTimeGenerator.GenerateTimestamp(), 10), //apns-expiration
 		}
 		query = h.HubURL.Query()
 	)
@@ -118,56 +179,34 @@ func (h *NotificationHub) sendDirect(ctx context.Context, n *Notification, devic
 		Path:     path.Join(h.HubURL.Path, "messages"),
 		RawQuery: query.Encode(),
 	}
-	raw, response, err := h.exec(ctx, postMethod, _url, headers, bytes.NewBuffer(n.Payload))
+	raw, response, err := h.exec(ctx, "SendDirect", postMethod, _url, headers, bytes.NewBuffer(n.Payload))
 	if err != nil {
 		return
 	}
+	h.observeSend(n.Format)
 	telemetry, err = NewNotificationTelemetryFromHTTPResponse(response)
 	return
 }
 
 func (h *NotificationHub) sendDirectBatch(ctx context.Context, n *Notification, deviceHandles []string) (raw []byte, telemetry *NotificationTelemetry, err error) {
-	if len(deviceHandles) > 1000 {
+	if len(deviceHandles) > maxDirectBatchSize {
 		err = errors.New("you can not batch send to more than 1,000 devices")
 		return
 	}
 
-	buf := &bytes.Buffer{}
-	multi := multipart.NewWriter(buf)
-
-	var part io.Writer
-	part, err = multi.CreatePart(textproto.MIMEHeader{
-		"Content-Type":        []string{n.Format.GetContentType()},
-		"Content-Disposition": []string{"inline; name=notification"},
-	})
-	if err != nil {
-		return
-	}
-	if _, err = part.Write(n.Payload); err != nil {
-		return
-	}
-
-	part, err = multi.CreatePart(textproto.MIMEHeader{
-		"Content-Type":        []string{"application/json"},
-		"Content-Disposition": []string{"inline; name=devices"},
-	})
-	if err != nil {
-		return
-	}
-	var handles []byte
-	handles, err = json.Marshal(deviceHandles)
-	if err != nil {
-		return
-	}
-	if _, err = part.Write(handles); err != nil {
+	buf := batchBufferPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	var contentType string
+	if contentType, err = writeBatchBody(buf, n, deviceHandles); err != nil {
+		batchBufferPool.Put(buf)
 		return
 	}
 
 	var (
 		headers = Headers{
-			"Content-Type":                  multi.FormDataContentType(),
+			"Content-Type":                  contentType,
 			"ServiceBusNotification-Format": string(n.Format),
-			"X-Apns-Expiration":             string(h.expirationTimeGenerator.GenerateTimestamp()), //apns-expiration
+			"X-Apns-Expiration":             strconv.FormatInt(h.expirationTimeGenerator.GenerateTimestamp(), 10), //apns-expiration
 		}
 		query = h.HubURL.Query()
 	)
@@ -178,10 +217,99 @@ func (h *NotificationHub) sendDirectBatch(ctx context.Context, n *Notification,
 		Path:     path.Join(h.HubURL.Path, "messages", "$batch"),
 		RawQuery: query.Encode(),
 	}
-	raw, response, err := h.exec(ctx, postMethod, _url, headers, buf)
+	var response *http.Response
+	raw, response, err = h.exec(withBatchSize(ctx, len(deviceHandles)), "SendDirectBatch", postMethod, _url, headers, newPooledBody(buf))
 	if err != nil {
 		return
 	}
+	h.observeSend(n.Format)
 	telemetry, err = NewNotificationTelemetryFromHTTPResponse(response)
 	return
 }
+
+// writeBatchBody writes the multipart body of a batch direct send and returns its content type
+func writeBatchBody(w io.Writer, n *Notification, deviceHandles []string) (string, error) {
+	multi := multipart.NewWriter(w)
+
+	part, err := multi.CreatePart(textproto.MIMEHeader{
+		"Content-Type":        []string{n.Format.GetContentType()},
+		"Content-Disposition": []string{"inline; name=notification"},
+	})
+	if err != nil {
+		return "", err
+	}
+	if _, err = part.Write(n.Payload); err != nil {
+		return "", err
+	}
+
+	part, err = multi.CreatePart(textproto.MIMEHeader{
+		"Content-Type":        []string{"application/json"},
+		"Content-Disposition": []string{"inline; name=devices"},
+	})
+	if err != nil {
+		return "", err
+	}
+	if err = json.NewEncoder(part).Encode(deviceHandles); err != nil {
+		return "", err
+	}
+	if err = multi.Close(); err != nil {
+		return "", err
+	}
+	return multi.FormDataContentType(), nil
+}
+
+// newPooledBody returns a request body reading buf, which is returned to the pool when the body is closed
+func newPooledBody(buf *bytes.Buffer) *pooledBody {
+	return &pooledBody{buf: buf, reader: bytes.NewReader(buf.Bytes())}
+}
+
+// Read reads the body until it is closed
+func (b *pooledBody) Read(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.buf == nil {
+		return 0, errBodyClosed
+	}
+	return b.reader.Read(p)
+}
+
+// Len returns the number of unread bytes
+func (b *pooledBody) Len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.buf == nil {
+		return 0
+	}
+	return b.reader.Len()
+}
+
+// Close returns the buffer to the pool, later reads fail
+func (b *pooledBody) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.buf != nil && b.buf.Cap() <= maxPooledBatchBufferSize {
+		batchBufferPool.Put(b.buf)
+	}
+	b.buf, b.reader = nil, nil
+	return nil
+}
+
+// size returns the length of the whole body
+func (b *pooledBody) size() int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.reader == nil {
+		return 0
+	}
+	return b.reader.Size()
+}
+
+// getBody returns a copy of the body, which stays readable after the body is closed
+func (b *pooledBody) getBody() (io.ReadCloser, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.buf == nil {
+		return nil, errBodyClosed
+	}
+	return ioutil.NopCloser(bytes.NewReader(append([]byte(nil), b.buf.Bytes()...))), nil
+}
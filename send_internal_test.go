@@ -0,0 +1,158 @@
+package notificationhubs
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/textproto"
+	"testing"
+)
+
+func batchTestHandles(count int) []string {
+	handles := make([]string, count)
+	for i := range handles {
+		handles[i] = fmt.Sprintf("%064x", i)
+	}
+	return handles
+}
+
+func TestPooledBody(t *testing.T) {
+	buf := batchBufferPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	buf.WriteString("batch body")
+	body := newPooledBody(buf)
+
+	if body.size() != 10 || body.Len() != 10 {
+		t.Errorf("size %d, Len %d. Expected 10", body.size(), body.Len())
+	}
+	head := make([]byte, 5)
+	if _, err := io.ReadFull(body, head); err != nil || string(head) != "batch" {
+		t.Errorf("Read. Expected batch, got %q %v", head, err)
+	}
+	copied, err := body.getBody()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = body.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err = body.Close(); err != nil {
+		t.Errorf("second Close. Expected nil, got %v", err)
+	}
+	if _, err = body.Read(head); err != errBodyClosed {
+		t.Errorf("Read after Close. Expected %v, got %v", errBodyClosed, err)
+	}
+	if _, err = body.getBody(); err != errBodyClosed {
+		t.Errorf("getBody after Close. Expected %v, got %v", errBodyClosed, err)
+	}
+
+	// the buffer may be reused once the body is closed, the copy is not affected
+	buf.Reset()
+	buf.WriteString("reused")
+	if b, _ := ioutil.ReadAll(copied); string(b) != "batch body" {
+		t.Errorf("getBody. Expected batch body, got %q", b)
+	}
+}
+
+func TestWriteBatchBody(t *testing.T) {
+	var (
+		buf     bytes.Buffer
+		n, _    = NewNotification(AppleFormat, []byte(`{"aps":{"alert":"hello"}}`))
+		handles = batchTestHandles(3)
+	)
+	handlesJSON, _ := json.Marshal(handles)
+
+	contentType, err := writeBatchBody(&buf, n, handles)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		reader   = multipart.NewReader(&buf, params["boundary"])
+		expected = []struct {
+			name, contentType, body string
+		}{
+			{"notification", "application/json", string(n.Payload)},
+			{"devices", "application/json", string(handlesJSON) + "\n"},
+		}
+	)
+	for _, part := range expected {
+		p, err := reader.NextPart()
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := ioutil.ReadAll(p)
+		if p.Header.Get("Content-Disposition") != "inline; name="+part.name || p.Header.Get("Content-Type") != part.contentType || string(body) != part.body {
+			t.Errorf("part %s. Expected %s %s, got %s %s", part.name, part.contentType, part.body, p.Header.Get("Content-Type"), body)
+		}
+	}
+	if _, err = reader.NextPart(); err != io.EOF {
+		t.Errorf("expected closing boundary, got %v", err)
+	}
+}
+
+// writeBatchBodyUnpooled builds the batch body as before pooling, as the baseline of the benchmarks
+func writeBatchBodyUnpooled(n *Notification, deviceHandles []string) (*bytes.Buffer, error) {
+	buf := &bytes.Buffer{}
+	multi := multipart.NewWriter(buf)
+	part, err := multi.CreatePart(textproto.MIMEHeader{
+		"Content-Type":        []string{n.Format.GetContentType()},
+		"Content-Disposition": []string{"inline; name=notification"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if _, err = part.Write(n.Payload); err != nil {
+		return nil, err
+	}
+	part, err = multi.CreatePart(textproto.MIMEHeader{
+		"Content-Type":        []string{"application/json"},
+		"Content-Disposition": []string{"inline; name=devices"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	handles, err := json.Marshal(deviceHandles)
+	if err != nil {
+		return nil, err
+	}
+	_, err = part.Write(handles)
+	return buf, err
+}
+
+func BenchmarkBatchBody(b *testing.B) {
+	var (
+		n, _    = NewNotification(AppleFormat, []byte(`{"aps":{"alert":"hello"}}`))
+		handles = batchTestHandles(maxDirectBatchSize)
+	)
+
+	b.Run("unpooled", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := writeBatchBodyUnpooled(n, handles); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("pooled", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			buf := batchBufferPool.Get().(*bytes.Buffer)
+			buf.Reset()
+			if _, err := writeBatchBody(buf, n, handles); err != nil {
+				b.Fatal(err)
+			}
+			batchBufferPool.Put(buf)
+		}
+	})
+}
@@ -5,11 +5,13 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"net/url"
 	"strconv"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -252,6 +254,10 @@ func Test_NotificationSendDirectBatchError(t *testing.T) {
 		if reqURL := req.URL.String(); reqURL != wantURL {
 			t.Errorf(errfmt, "URL", wantURL, reqURL)
 		}
+		body, _ := ioutil.ReadAll(req.Body)
+		if req.ContentLength != int64(len(body)) || req.GetBody == nil {
+			t.Errorf(errfmt, "ContentLength", len(body), req.ContentLength)
+		}
 		return nil, nil, expectedError
 	}
 
@@ -351,3 +357,116 @@ func Test_NotificationHubSendAppleAlertNotification(t *testing.T) {
 		t.Errorf(errfmt, "error", nil, err)
 	}
 }
+
+func Test_NotificationSendToUser(t *testing.T) {
+	var (
+		nhub, notification, mockClient = initNotificationTestItems()
+		userIDs                        []string
+		mu                             sync.Mutex
+		gotTags                        = map[string]bool{}
+	)
+	for i := 0; i < 45; i++ {
+		userIDs = append(userIDs, fmt.Sprintf("user%d", i))
+	}
+
+	mockClient.execFunc = func(obtainedReq *http.Request) ([]byte, *http.Response, error) {
+		if gotURL := obtainedReq.URL.String(); gotURL != messagesURL {
+			t.Errorf(errfmt, "URL", messagesURL, gotURL)
+		}
+		tags := obtainedReq.Header.Get("ServiceBusNotification-Tags")
+		mu.Lock()
+		gotTags[tags] = true
+		mu.Unlock()
+
+		firstUser := strings.TrimSuffix(strings.TrimPrefix(strings.Split(tags, " ")[0], "$UserId:{"), "}")
+		mockResponse := http.Response{
+			Header: http.Header{
+				"Location": []string{
+					fmt.Sprintf("https://messages.servicebus.windows.net/messagebus/messages/%s?api-version=2016-10", firstUser),
+				},
+			},
+		}
+		return nil, &mockResponse, nil
+	}
+
+	telemetry, err := nhub.SendToUser(context.Background(), notification, userIDs...)
+	if err != nil {
+		t.Fatalf(errfmt, "error", nil, err)
+	}
+	if len(telemetry) != 3 {
+		t.Fatalf(errfmt, "telemetry count", 3, len(telemetry))
+	}
+	if telemetry[2].NotificationMessageID != "user40" {
+		t.Errorf(errfmt, "telemetry", "user40", telemetry[2].NotificationMessageID)
+	}
+	if len(gotTags) != 3 {
+		t.Fatalf(errfmt, "request count", 3, len(gotTags))
+	}
+	for tags := range gotTags {
+		if n := strings.Count(tags, "||") + 1; n != 20 && n != 5 {
+			t.Errorf(errfmt, "tags in request", "20 or 5", n)
+		}
+	}
+	expectedLast := "$UserId:{user40} || $UserId:{user41} || $UserId:{user42} || $UserId:{user43} || $UserId:{user44}"
+	if !gotTags[expectedLast] {
+		t.Errorf(errfmt, "ServiceBusNotification-Tags", expectedLast, gotTags)
+	}
+}
+
+func Test_NotificationSendToInstallations(t *testing.T) {
+	var (
+		expectedError                  = errors.New("test error")
+		nhub, notification, mockClient = initNotificationTestItems()
+	)
+
+	mockClient.execFunc = func(obtainedReq *http.Request) ([]byte, *http.Response, error) {
+		expectedTags := "$InstallationId:{inst1} || $InstallationId:{inst2}"
+		if gotTags := obtainedReq.Header.Get("ServiceBusNotification-Tags"); gotTags != expectedTags {
+			t.Errorf(errfmt, "ServiceBusNotification-Tags", expectedTags, gotTags)
+		}
+		return nil, nil, expectedError
+	}
+
+	telemetry, err := nhub.SendToInstallations(context.Background(), notification, "inst1", "inst2")
+	if telemetry != nil {
+		t.Errorf(errfmt, "telemetry", nil, telemetry)
+	}
+	if err == nil || !strings.Contains(err.Error(), expectedError.Error()) {
+		t.Errorf(errfmt, "SendToInstallations error", expectedError, err)
+	}
+
+	if _, err = nhub.SendToInstallations(context.Background(), notification); err == nil {
+		t.Errorf(errfmt, "SendToInstallations error", "no tags to send to", err)
+	}
+}
+
+func BenchmarkSendDirectBatch(b *testing.B) {
+	var (
+		nhub, mockClient = initTestItems()
+		notification, _  = NewNotification(AppleFormat, []byte(`{"aps":{"alert":"hello"}}`))
+		handles          = make([]string, 1000)
+		mockResponse     = &http.Response{
+			Header: http.Header{
+				"Location": []string{"https://testhub-ns.servicebus.windows.net/testhub/messages/1?api-version=2016-07"},
+			},
+		}
+	)
+	for i := range handles {
+		handles[i] = fmt.Sprintf("%064x", i)
+	}
+	mockClient.execFunc = func(req *http.Request) ([]byte, *http.Response, error) {
+		// the transport reads and closes the body, returning its buffer to the pool
+		_, err := io.Copy(ioutil.Discard, req.Body)
+		req.Body.Close()
+		return nil, mockResponse, err
+	}
+
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			if _, _, err := nhub.SendDirectBatch(context.Background(), notification, handles...); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
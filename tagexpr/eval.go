@@ -0,0 +1,49 @@
+package tagexpr
+
+// TagSet is a set of device tags
+type TagSet map[string]struct{}
+
+// NewTagSet creates a set of the tags
+func NewTagSet(tags ...string) TagSet {
+	set := make(TagSet, len(tags))
+	for _, tag := range tags {
+		set[tag] = struct{}{}
+	}
+	return set
+}
+
+// Has identifies whether the tag is in the set
+func (s TagSet) Has(tag string) bool {
+	_, ok := s[tag]
+	return ok
+}
+
+// Match identifies whether a device with the tags would be targeted by the expression
+func Match(e Expr, tags []string) bool {
+	return Eval(e, NewTagSet(tags...))
+}
+
+// Eval evaluates the expression against a set of device tags
+func Eval(e Expr, tags TagSet) bool {
+	switch e := e.(type) {
+	case *TagExpr:
+		return tags.Has(e.Name)
+	case *OrExpr:
+		for _, operand := range e.Operands {
+			if Eval(operand, tags) {
+				return true
+			}
+		}
+		return false
+	case *AndExpr:
+		for _, operand := range e.Operands {
+			if !Eval(operand, tags) {
+				return false
+			}
+		}
+		return len(e.Operands) > 0
+	case *NotExpr:
+		return !Eval(e.Operand, tags)
+	}
+	return false
+}
@@ -0,0 +1,52 @@
+package tagexpr_test
+
+import (
+	"fmt"
+	"testing"
+
+	. "github.com/daresaydigital/azure-notificationhubs-go/tagexpr"
+)
+
+func TestMatch(t *testing.T) {
+	var (
+		devices = map[string][]string{
+			"A": {"tag1", "tag2"},
+			"B": {"tag2", "tag3"},
+			"C": {"tag1", "tag2", "tag3"},
+		}
+		testCases = []struct {
+			expression string
+			expected   map[string]bool
+		}{
+			{
+				expression: "tag1 || tag2",
+				expected:   map[string]bool{"A": true, "B": true, "C": true},
+			},
+			{
+				expression: "tag1 && tag2",
+				expected:   map[string]bool{"A": true, "B": false, "C": true},
+			},
+			{
+				expression: "tag1 && tag2 && !tag3",
+				expected:   map[string]bool{"A": true, "B": false, "C": false},
+			},
+			{
+				expression: "!tag1",
+				expected:   map[string]bool{"A": false, "B": true, "C": false},
+			},
+			{
+				expression: "!(tag1 || tag3)",
+				expected:   map[string]bool{"A": false, "B": false, "C": false},
+			},
+		}
+	)
+
+	for _, testCase := range testCases {
+		expr := MustParse(testCase.expression)
+		for device, tags := range devices {
+			if got := Match(expr, tags); got != testCase.expected[device] {
+				t.Errorf(errfmt, fmt.Sprintf("%q matching device %s", testCase.expression, device), testCase.expected[device], got)
+			}
+		}
+	}
+}
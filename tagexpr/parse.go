@@ -0,0 +1,198 @@
+package tagexpr
+
+import (
+	"fmt"
+	"strings"
+)
+
+type (
+	// SyntaxError is returned when an expression can not be parsed
+	SyntaxError struct {
+		Expression string
+		Offset     int
+		Msg        string
+	}
+
+	tokenKind int
+
+	token struct {
+		kind   tokenKind
+		value  string
+		offset int
+	}
+
+	parser struct {
+		input  string
+		tokens []token
+		pos    int
+	}
+)
+
+const (
+	tagToken tokenKind = iota
+	orToken
+	andToken
+	notToken
+	openToken
+	closeToken
+	endToken
+)
+
+// Error returns the error message
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("tagexpr: %s at offset %d in %q", e.Msg, e.Offset, e.Expression)
+}
+
+// Parse parses a tag expression such as "(follows_RedSox || follows_Cardinals) && location_Boston"
+// The returned expression is not validated, use Validate to check it against the hub limits
+func Parse(s string) (Expr, error) {
+	tokens, err := tokenize(s)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{input: s, tokens: tokens}
+	e, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != endToken {
+		return nil, p.errorf(t, "unexpected %q", t.value)
+	}
+	return e, nil
+}
+
+// MustParse is like Parse but panics if the expression can not be parsed
+func MustParse(s string) Expr {
+	e, err := Parse(s)
+	if err != nil {
+		panic(err)
+	}
+	return e
+}
+
+// tokenize splits the expression into tokens
+func tokenize(s string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(s); {
+		switch c := s[i]; {
+		case c == ' ' || c == '\t' || c == '\r' || c == '\n':
+			i++
+		case c == '(':
+			tokens = append(tokens, token{openToken, "(", i})
+			i++
+		case c == ')':
+			tokens = append(tokens, token{closeToken, ")", i})
+			i++
+		case c == '!':
+			tokens = append(tokens, token{notToken, "!", i})
+			i++
+		case strings.HasPrefix(s[i:], "||"):
+			tokens = append(tokens, token{orToken, "||", i})
+			i += 2
+		case strings.HasPrefix(s[i:], "&&"):
+			tokens = append(tokens, token{andToken, "&&", i})
+			i += 2
+		case c == '|' || c == '&':
+			return nil, &SyntaxError{Expression: s, Offset: i, Msg: fmt.Sprintf("unexpected %q", c)}
+		default:
+			start := i
+			for i < len(s) && !isDelimiter(s[i]) {
+				if s[i] == '{' {
+					// reserved tags such as $UserId:{id} may contain any character within the braces
+					end := strings.IndexByte(s[i:], '}')
+					if end < 0 {
+						return nil, &SyntaxError{Expression: s, Offset: i, Msg: "unterminated '{'"}
+					}
+					i += end
+				}
+				i++
+			}
+			tokens = append(tokens, token{tagToken, s[start:i], start})
+		}
+	}
+	return append(tokens, token{endToken, "end of expression", len(s)}), nil
+}
+
+// isDelimiter identifies whether c ends a tag
+func isDelimiter(c byte) bool {
+	switch c {
+	case ' ', '\t', '\r', '\n', '(', ')', '!', '|', '&':
+		return true
+	}
+	return false
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != endToken {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) errorf(t token, format string, args ...interface{}) error {
+	return &SyntaxError{Expression: p.input, Offset: t.offset, Msg: fmt.Sprintf(format, args...)}
+}
+
+// parseOr parses and ('||' and)*
+func (p *parser) parseOr() (Expr, error) {
+	operands, err := p.parseOperands(orToken, p.parseAnd)
+	if err != nil {
+		return nil, err
+	}
+	return Or(operands...), nil
+}
+
+// parseAnd parses unary ('&&' unary)*
+func (p *parser) parseAnd() (Expr, error) {
+	operands, err := p.parseOperands(andToken, p.parseUnary)
+	if err != nil {
+		return nil, err
+	}
+	return And(operands...), nil
+}
+
+// parseOperands parses operands separated by the operator
+func (p *parser) parseOperands(operator tokenKind, parseOperand func() (Expr, error)) ([]Expr, error) {
+	var operands []Expr
+	for {
+		operand, err := parseOperand()
+		if err != nil {
+			return nil, err
+		}
+		operands = append(operands, operand)
+		if p.peek().kind != operator {
+			return operands, nil
+		}
+		p.next()
+	}
+}
+
+// parseUnary parses '!' unary | '(' or ')' | tag
+func (p *parser) parseUnary() (Expr, error) {
+	switch t := p.next(); t.kind {
+	case notToken:
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return Not(operand), nil
+	case openToken:
+		e, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.next(); closing.kind != closeToken {
+			return nil, p.errorf(closing, "expected ')' but got %q", closing.value)
+		}
+		return e, nil
+	case tagToken:
+		return Tag(t.value), nil
+	default:
+		return nil, p.errorf(t, "expected tag but got %q", t.value)
+	}
+}
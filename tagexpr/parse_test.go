@@ -0,0 +1,89 @@
+package tagexpr_test
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	. "github.com/daresaydigital/azure-notificationhubs-go/tagexpr"
+)
+
+func TestParse(t *testing.T) {
+	var testCases = []struct {
+		input     string
+		expected  Expr
+		canonical string
+	}{
+		{
+			input:     "tag1",
+			expected:  Tag("tag1"),
+			canonical: "tag1",
+		},
+		{
+			input:     "tag1||tag2 ||  tag3",
+			expected:  Or(Tags("tag1", "tag2", "tag3")...),
+			canonical: "tag1 || tag2 || tag3",
+		},
+		{
+			input:     "(follows_RedSox || follows_Cardinals) && location_Boston",
+			expected:  And(Or(Tag("follows_RedSox"), Tag("follows_Cardinals")), Tag("location_Boston")),
+			canonical: "(follows_RedSox || follows_Cardinals) && location_Boston",
+		},
+		{
+			input:     "a && b || !c && d",
+			expected:  Or(And(Tag("a"), Tag("b")), And(Not(Tag("c")), Tag("d"))),
+			canonical: "a && b || !c && d",
+		},
+		{
+			input:     "((a)) && !(b || c)",
+			expected:  And(Tag("a"), Not(Or(Tag("b"), Tag("c")))),
+			canonical: "a && !(b || c)",
+		},
+		{
+			input:     "$UserId:{john (doe)} || $InstallationId:{abc}",
+			expected:  Or(Tag("$UserId:{john (doe)}"), Tag("$InstallationId:{abc}")),
+			canonical: "$UserId:{john (doe)} || $InstallationId:{abc}",
+		},
+	}
+
+	for i, testCase := range testCases {
+		got, err := Parse(testCase.input)
+		if err != nil {
+			t.Errorf(errfmt, fmt.Sprintf("case %d error", i), nil, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, testCase.expected) {
+			t.Errorf(errfmt, fmt.Sprintf("case %d expression", i), testCase.expected, got)
+		}
+		if got.String() != testCase.canonical {
+			t.Errorf(errfmt, fmt.Sprintf("case %d String()", i), testCase.canonical, got.String())
+		}
+	}
+}
+
+func TestParseError(t *testing.T) {
+	var testCases = []struct {
+		input  string
+		offset int
+	}{
+		{input: "", offset: 0},
+		{input: "a ||", offset: 4},
+		{input: "a | b", offset: 2},
+		{input: "(a || b", offset: 7},
+		{input: "a b", offset: 2},
+		{input: "a && )", offset: 5},
+		{input: "$UserId:{abc", offset: 8},
+	}
+
+	for i, testCase := range testCases {
+		_, err := Parse(testCase.input)
+		syntaxErr, ok := err.(*SyntaxError)
+		if !ok {
+			t.Errorf(errfmt, fmt.Sprintf("case %d error", i), "*SyntaxError", err)
+			continue
+		}
+		if syntaxErr.Offset != testCase.offset {
+			t.Errorf(errfmt, fmt.Sprintf("case %d offset", i), testCase.offset, syntaxErr.Offset)
+		}
+	}
+}
@@ -0,0 +1,181 @@
+// Package tagexpr builds, parses and validates Azure Notification Hubs tag expressions.
+// Read more at https://docs.microsoft.com/en-us/azure/notification-hubs/notification-hubs-tags-segment-push-message
+package tagexpr
+
+import "strings"
+
+type (
+	// Expr is a tag expression
+	Expr interface {
+		// String renders the expression in the canonical form accepted by the hub
+		String() string
+
+		precedence() int
+	}
+
+	// TagExpr matches devices having the tag
+	TagExpr struct {
+		Name string
+	}
+
+	// OrExpr matches devices matching any of the operands
+	OrExpr struct {
+		Operands []Expr
+	}
+
+	// AndExpr matches devices matching all of the operands
+	AndExpr struct {
+		Operands []Expr
+	}
+
+	// NotExpr matches devices not matching the operand
+	NotExpr struct {
+		Operand Expr
+	}
+)
+
+// Operator precedence, lowest first
+const (
+	orPrecedence = iota
+	andPrecedence
+	notPrecedence
+	tagPrecedence
+)
+
+// Tag returns an expression matching a single tag
+func Tag(name string) Expr {
+	return &TagExpr{Name: name}
+}
+
+// Tags returns one tag expression per name
+func Tags(names ...string) []Expr {
+	exprs := make([]Expr, len(names))
+	for i, name := range names {
+		exprs[i] = Tag(name)
+	}
+	return exprs
+}
+
+// Or returns an expression matching any of the operands
+// Nested OR expressions are flattened and a single operand is returned as is
+func Or(operands ...Expr) Expr {
+	var flat []Expr
+	for _, operand := range operands {
+		if or, ok := operand.(*OrExpr); ok {
+			flat = append(flat, or.Operands...)
+		} else if operand != nil {
+			flat = append(flat, operand)
+		}
+	}
+	if len(flat) == 1 {
+		return flat[0]
+	}
+	return &OrExpr{Operands: flat}
+}
+
+// And returns an expression matching all of the operands
+// Nested AND expressions are flattened and a single operand is returned as is
+func And(operands ...Expr) Expr {
+	var flat []Expr
+	for _, operand := range operands {
+		if and, ok := operand.(*AndExpr); ok {
+			flat = append(flat, and.Operands...)
+		} else if operand != nil {
+			flat = append(flat, operand)
+		}
+	}
+	if len(flat) == 1 {
+		return flat[0]
+	}
+	return &AndExpr{Operands: flat}
+}
+
+// Not returns an expression matching devices not matching the operand
+func Not(operand Expr) Expr {
+	return &NotExpr{Operand: operand}
+}
+
+// String returns the tag
+func (e *TagExpr) String() string {
+	return e.Name
+}
+
+// String joins the operands with ||
+func (e *OrExpr) String() string {
+	return join(e.Operands, " || ", orPrecedence)
+}
+
+// String joins the operands with &&
+func (e *AndExpr) String() string {
+	return join(e.Operands, " && ", andPrecedence)
+}
+
+// String prefixes the operand with !
+func (e *NotExpr) String() string {
+	return "!" + wrap(e.Operand, notPrecedence)
+}
+
+func (e *TagExpr) precedence() int { return tagPrecedence }
+func (e *OrExpr) precedence() int  { return orPrecedence }
+func (e *AndExpr) precedence() int { return andPrecedence }
+func (e *NotExpr) precedence() int { return notPrecedence }
+
+// join renders the operands separated by sep
+func join(operands []Expr, sep string, precedence int) string {
+	parts := make([]string, len(operands))
+	for i, operand := range operands {
+		parts[i] = wrap(operand, precedence)
+	}
+	return strings.Join(parts, sep)
+}
+
+// wrap renders e, adding parentheses if it binds looser than the parent
+func wrap(e Expr, parent int) string {
+	if e.precedence() < parent {
+		return "(" + e.String() + ")"
+	}
+	return e.String()
+}
+
+// TagNames returns every tag in the expression, in order of appearance
+func TagNames(e Expr) []string {
+	var names []string
+	walk(e, func(t *TagExpr) {
+		names = append(names, t.Name)
+	})
+	return names
+}
+
+// IsOrOnly identifies whether the expression only uses the || operator
+func IsOrOnly(e Expr) bool {
+	switch e := e.(type) {
+	case *TagExpr:
+		return true
+	case *OrExpr:
+		for _, operand := range e.Operands {
+			if !IsOrOnly(operand) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// walk calls fn for every tag in the expression
+func walk(e Expr, fn func(*TagExpr)) {
+	switch e := e.(type) {
+	case *TagExpr:
+		fn(e)
+	case *OrExpr:
+		for _, operand := range e.Operands {
+			walk(operand, fn)
+		}
+	case *AndExpr:
+		for _, operand := range e.Operands {
+			walk(operand, fn)
+		}
+	case *NotExpr:
+		walk(e.Operand, fn)
+	}
+}
@@ -0,0 +1,107 @@
+package tagexpr_test
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	. "github.com/daresaydigital/azure-notificationhubs-go/tagexpr"
+)
+
+const errfmt = "Expected %s: \n%v\ngot:\n%v"
+
+func TestExpr_String(t *testing.T) {
+	var testCases = []struct {
+		expr     Expr
+		expected string
+	}{
+		{
+			expr:     Tag("tag1"),
+			expected: "tag1",
+		},
+		{
+			expr:     Or(Tags("tag1", "tag2")...),
+			expected: "tag1 || tag2",
+		},
+		{
+			expr:     And(Or(Tag("follows_RedSox"), Tag("follows_Cardinals")), Tag("location_Boston")),
+			expected: "(follows_RedSox || follows_Cardinals) && location_Boston",
+		},
+		{
+			expr:     Or(And(Tag("a"), Tag("b")), Tag("c")),
+			expected: "a && b || c",
+		},
+		{
+			expr:     And(Tag("tag1"), Tag("tag2"), Not(Tag("tag3"))),
+			expected: "tag1 && tag2 && !tag3",
+		},
+		{
+			expr:     Not(Or(Tag("a"), Tag("b"))),
+			expected: "!(a || b)",
+		},
+		{
+			expr:     Or(Or(Tag("a"), Tag("b")), Or(Tag("c"))),
+			expected: "a || b || c",
+		},
+	}
+
+	for i, testCase := range testCases {
+		if got := testCase.expr.String(); got != testCase.expected {
+			t.Errorf(errfmt, fmt.Sprintf("case %d String()", i), testCase.expected, got)
+		}
+	}
+}
+
+func TestTagNames(t *testing.T) {
+	expr := And(Or(Tags("a", "b")...), Not(Tag("c")))
+	if got, expected := TagNames(expr), []string{"a", "b", "c"}; !reflect.DeepEqual(got, expected) {
+		t.Errorf(errfmt, "TagNames", expected, got)
+	}
+	if IsOrOnly(expr) {
+		t.Errorf(errfmt, "IsOrOnly", false, true)
+	}
+	if !IsOrOnly(Or(Tags("a", "b")...)) {
+		t.Errorf(errfmt, "IsOrOnly", true, false)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	var (
+		tooMany   = numberedTags(MaxOrTags + 1)
+		testCases = []struct {
+			expr  Expr
+			valid bool
+		}{
+			{expr: Tag("tag_1@domain#x.y:z-w"), valid: true},
+			{expr: Tag("$UserId:{user 1}"), valid: true},
+			{expr: Tag("$InstallationId:{0a92196c-20c3-4308-8046-c384c902d0ff}"), valid: true},
+			{expr: Tag("$UserId:{user"), valid: false},
+			{expr: Tag("tag with space"), valid: false},
+			{expr: Tag(""), valid: false},
+			{expr: Tag(strings.Repeat("a", MaxTagLength+1)), valid: false},
+			{expr: Or(Tags(tooMany[:MaxOrTags]...)...), valid: true},
+			{expr: Or(Tags(tooMany...)...), valid: false},
+			{expr: And(Tags("a", "b", "c", "d", "e", "f")...), valid: true},
+			{expr: And(Tags("a", "b", "c", "d", "e", "f", "g")...), valid: false},
+			{expr: Or(Not(Tag("a")), Tag("b")), valid: true},
+			{expr: Or(append([]Expr{Not(Tag("a"))}, Tags("b", "c", "d", "e", "f", "g")...)...), valid: false},
+			{expr: &OrExpr{}, valid: false},
+			{expr: Not(nil), valid: false},
+		}
+	)
+	for i, testCase := range testCases {
+		err := Validate(testCase.expr)
+		if (err == nil) != testCase.valid {
+			t.Errorf(errfmt, fmt.Sprintf("case %d valid", i), testCase.valid, err)
+		}
+	}
+}
+
+func numberedTags(n int) []string {
+	tags := make([]string, n)
+	for i := range tags {
+		tags[i] = fmt.Sprintf("tag%d", i)
+	}
+	return tags
+}
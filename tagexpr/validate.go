@@ -0,0 +1,114 @@
+package tagexpr
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Hub limits for tag expressions
+const (
+	// MaxTagLength is the maximum length of a single tag
+	MaxTagLength = 120
+	// MaxOrTags is the maximum number of tags in an expression only using ||
+	MaxOrTags = 20
+	// MaxTags is the maximum number of tags in an expression using && or !
+	MaxTags = 6
+)
+
+// reservedTagPrefixes are the prefixes of the tags the hub adds to installations
+var reservedTagPrefixes = []string{"$InstallationId:{", "$UserId:{"}
+
+// Validate checks every tag in the expression and the number of tags against the hub limits
+func Validate(e Expr) error {
+	if e == nil {
+		return fmt.Errorf("tagexpr: empty expression")
+	}
+	if err := validateOperands(e); err != nil {
+		return err
+	}
+	names := TagNames(e)
+	for _, name := range names {
+		if err := ValidateTag(name); err != nil {
+			return err
+		}
+	}
+	if limit := Limit(e); len(names) > limit {
+		return fmt.Errorf("tagexpr: expression has %d tags, the limit is %d", len(names), limit)
+	}
+	return nil
+}
+
+// Limit returns the maximum number of tags allowed in an expression of the same shape as e
+func Limit(e Expr) int {
+	if IsOrOnly(e) {
+		return MaxOrTags
+	}
+	return MaxTags
+}
+
+// ValidateTag checks that the tag is not too long and only contains
+// alphanumeric characters and '_', '@', '#', '.', ':' or '-'
+// The reserved $InstallationId:{id} and $UserId:{id} tags are also accepted
+func ValidateTag(tag string) error {
+	if len(tag) == 0 {
+		return fmt.Errorf("tagexpr: empty tag")
+	}
+	if len(tag) > MaxTagLength {
+		return fmt.Errorf("tagexpr: tag %q is longer than %d characters", tag, MaxTagLength)
+	}
+	for _, prefix := range reservedTagPrefixes {
+		if strings.HasPrefix(tag, prefix) {
+			if !strings.HasSuffix(tag, "}") || strings.ContainsAny(tag[len(prefix):len(tag)-1], "{}") {
+				return fmt.Errorf("tagexpr: malformed reserved tag %q", tag)
+			}
+			return nil
+		}
+	}
+	for _, c := range tag {
+		if !isTagChar(c) {
+			return fmt.Errorf("tagexpr: tag %q contains invalid character %q", tag, c)
+		}
+	}
+	return nil
+}
+
+// isTagChar identifies whether c is allowed in a tag
+func isTagChar(c rune) bool {
+	switch {
+	case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		return true
+	}
+	return strings.ContainsRune("_@#.:-", c)
+}
+
+// validateOperands checks that no operator is missing its operands
+func validateOperands(e Expr) error {
+	switch e := e.(type) {
+	case *TagExpr:
+		return nil
+	case *OrExpr:
+		return validateAll(e.Operands, "||")
+	case *AndExpr:
+		return validateAll(e.Operands, "&&")
+	case *NotExpr:
+		if e.Operand == nil {
+			return fmt.Errorf("tagexpr: ! without operand")
+		}
+		return validateOperands(e.Operand)
+	case nil:
+		return fmt.Errorf("tagexpr: empty expression")
+	}
+	return fmt.Errorf("tagexpr: unknown expression %T", e)
+}
+
+func validateAll(operands []Expr, operator string) error {
+	if len(operands) == 0 {
+		return fmt.Errorf("tagexpr: %s without operands", operator)
+	}
+	for _, operand := range operands {
+		if err := validateOperands(operand); err != nil {
+			return err
+		}
+	}
+	return nil
+}
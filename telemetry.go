@@ -1,31 +1,191 @@
 package notificationhubs
 
 import (
+	"bufio"
+	"bytes"
 	"context"
+	"encoding/json"
 	"encoding/xml"
 	"errors"
 	"net/http"
 	"net/url"
 	"path"
 	"regexp"
+	"strings"
+	"time"
 )
 
 // NotificationDetails reads one specific registration
 func (h *NotificationHub) NotificationDetails(ctx context.Context, notificationID string) (details *NotificationDetails, raw []byte, err error) {
+	details, raw, _, err = h.notificationDetails(ctx, notificationID)
+	return
+}
+
+// notificationDetails reads the notification details and returns the response for status inspection
+func (h *NotificationHub) notificationDetails(ctx context.Context, notificationID string) (details *NotificationDetails, raw []byte, response *http.Response, err error) {
 	var (
 		_url = h.generateAPIURL(path.Join("messages", notificationID))
 	)
 	_url.RawQuery = url.Values{apiVersionParam: {telemetryAPIVersionValue}}.Encode()
-	raw, _, err = h.exec(ctx, getMethod, _url, Headers{}, nil)
+	raw, response, err = h.exec(ctx, "NotificationDetails", getMethod, _url, Headers{}, nil)
 	if err != nil {
 		return
 	}
 	if err = xml.Unmarshal(raw, &details); err != nil {
 		return
 	}
+	details.normalize()
+	return
+}
+
+// normalize parses the time and target platform strings
+func (d *NotificationDetails) normalize() {
+	d.EnqueueTime = parseTelemetryTime(d.EnqueueTimeString)
+	d.StartTime = parseTelemetryTime(d.StartTimeString)
+	d.EndTime = parseTelemetryTime(d.EndTimeString)
+	d.EnqueueTimeString = nil
+	d.StartTimeString = nil
+	d.EndTimeString = nil
+
+	d.TargetPlatforms = nil
+	if d.TargetPlatformsString != nil {
+		for _, platform := range strings.Split(*d.TargetPlatformsString, ",") {
+			if platform = strings.TrimSpace(platform); platform != "" {
+				d.TargetPlatforms = append(d.TargetPlatforms, TargetPlatform(platform))
+			}
+		}
+	}
+	d.TargetPlatformsString = nil
+}
+
+// parseTelemetryTime parses a telemetry time, which lacks the time zone for some hubs
+func parseTelemetryTime(s *string) *time.Time {
+	if s == nil || *s == "" {
+		return nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, *s)
+	if err != nil {
+		if t, err = time.Parse("2006-01-02T15:04:05.9999999", *s); err != nil {
+			return nil
+		}
+	}
+	return &t
+}
+
+// PlatformOutcomes returns the outcome counts of every platform the notification was sent to
+func (d *NotificationDetails) PlatformOutcomes() map[TargetPlatform]*NotificationOutcomes {
+	outcomes := map[TargetPlatform]*NotificationOutcomes{}
+	for platform, counts := range map[TargetPlatform]*NotificationOutcomes{
+		ApplePlatform:        d.ApnsOutcomeCounts,
+		WindowsPlatform:      d.WnsOutcomeCounts,
+		WindowsphonePlatform: d.MpnsOutcomeCounts,
+		AdmPlatform:          d.AdmOutcomeCounts,
+		BaiduPlatform:        d.BaiduOutcomeCounts,
+		GcmPlatform:          d.GcmOutcomeCounts,
+		FcmV1Platform:        d.FcmV1OutcomeCounts,
+		XiaomiPlatform:       d.XiaomiOutcomeCounts,
+		BrowserPlatform:      d.BrowserOutcomeCounts,
+	} {
+		if counts != nil {
+			outcomes[platform] = counts
+		}
+	}
+	return outcomes
+}
+
+// Count returns the number of the outcome across all platforms
+func (d *NotificationDetails) Count(name NotificationOutcomeName) (count int) {
+	for _, outcomes := range d.PlatformOutcomes() {
+		count += outcomes.Count(name)
+	}
+	return
+}
+
+// Total returns the number of outcomes across all platforms
+func (d *NotificationDetails) Total() (total int) {
+	for _, outcomes := range d.PlatformOutcomes() {
+		total += outcomes.Total()
+	}
+	return
+}
+
+// SuccessRate returns the share of successful outcomes, between 0 and 1
+func (d *NotificationDetails) SuccessRate() float64 {
+	total := d.Total()
+	if total == 0 {
+		return 0
+	}
+	return float64(d.Count(Success)) / float64(total)
+}
+
+// IsTerminal identifies whether the notification will not change state anymore
+func (d *NotificationDetails) IsTerminal() bool {
+	return d.State.IsTerminal()
+}
+
+// Count returns the number of the outcome
+func (o *NotificationOutcomes) Count(name NotificationOutcomeName) (count int) {
+	for _, outcome := range o.Outcomes {
+		if outcome.Name == name {
+			count += outcome.Count
+		}
+	}
 	return
 }
 
+// Total returns the number of all outcomes
+func (o *NotificationOutcomes) Total() (total int) {
+	for _, outcome := range o.Outcomes {
+		total += outcome.Count
+	}
+	return
+}
+
+// PnsErrorDetails downloads the per device handle failures referenced by the notification details
+// and calls fn for every failure. Iteration stops at the first error returned by fn.
+// The blob is read in full, so it must fit in the MaxResponseBodySize of the HTTP client, 64 MiB by default.
+// The details are only available for Standard tier Notification Hubs
+func (h *NotificationHub) PnsErrorDetails(ctx context.Context, details *NotificationDetails, fn func(PnsErrorDetail) error) error {
+	if details == nil || details.PnsErrorDetailsURI == "" {
+		return nil
+	}
+	blobURL, err := url.Parse(details.PnsErrorDetailsURI)
+	if err != nil {
+		return err
+	}
+	raw, err := h.execBlob(ctx, "PnsErrorDetails", blobURL)
+	if err != nil {
+		return err
+	}
+	return parsePnsErrorDetails(raw, fn)
+}
+
+// parsePnsErrorDetails reads a PNS error details blob with one JSON record per line
+func parsePnsErrorDetails(raw []byte, fn func(PnsErrorDetail) error) error {
+	return forEachJSONLine(raw, func(line []byte) error {
+		var detail PnsErrorDetail
+		if err := json.Unmarshal(line, &detail); err != nil {
+			return err
+		}
+		return fn(detail)
+	})
+}
+
+// forEachJSONLine calls fn for every non empty line of a JSON lines blob
+func forEachJSONLine(raw []byte, fn func(line []byte) error) error {
+	scanner := bufio.NewScanner(bytes.NewReader(raw))
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		if err := fn(line); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
 // NewNotificationTelemetryFromLocationURL create Telemetry from Location URL
 func NewNotificationTelemetryFromLocationURL(url string) *NotificationTelemetry {
 	var re = regexp.MustCompile(`/messages/(?P<id>.*)\?api-version=`)
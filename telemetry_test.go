@@ -1,9 +1,18 @@
 package notificationhubs
 
 import (
+	"context"
+	"errors"
+	"io/ioutil"
 	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/daresaydigital/azure-notificationhubs-go/utils"
 )
 
 var tests = []struct {
@@ -51,3 +60,125 @@ func TestNewNotificationTelemetryFromHTTPResponse(t *testing.T) {
 		})
 	}
 }
+
+func TestPnsErrorDetails(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/pnserrors/ok.json":
+			data, _ := ioutil.ReadFile("./fixtures/pnsErrorDetails.json")
+			_, _ = w.Write(data)
+		case "/pnserrors/malformed.json":
+			_, _ = w.Write([]byte("not json\n"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	var (
+		hub        = newNotificationHub("Endpoint=sb://testhub-ns.servicebus.windows.net/;SharedAccessKeyName=name;SharedAccessKey=key", "testhub")
+		details    []PnsErrorDetail
+		timestamp1 = time.Date(2020, 4, 20, 9, 10, 13, 500000000, time.UTC)
+		timestamp2 = time.Date(2020, 4, 20, 9, 10, 13, 700000000, time.UTC)
+		expected   = []PnsErrorDetail{
+			{Platform: APNSPlatform, Handle: "ABCDEF", Outcome: WrongToken, Timestamp: &timestamp1},
+			{Platform: GCMPlatform, Handle: "ANDROIDID", Outcome: ExpiredChannel, Timestamp: &timestamp2},
+		}
+		collect = func(detail PnsErrorDetail) error {
+			details = append(details, detail)
+			return nil
+		}
+	)
+
+	err := hub.PnsErrorDetails(context.Background(), &NotificationDetails{PnsErrorDetailsURI: server.URL + "/pnserrors/ok.json?sig=abc"}, collect)
+	if err != nil {
+		t.Fatalf("PnsErrorDetails() error = %v", err)
+	}
+	if !reflect.DeepEqual(details, expected) {
+		t.Errorf("PnsErrorDetails() = %v, want %v", details, expected)
+	}
+
+	stop := errors.New("stop")
+	err = hub.PnsErrorDetails(context.Background(), &NotificationDetails{PnsErrorDetailsURI: server.URL + "/pnserrors/ok.json"}, func(PnsErrorDetail) error {
+		return stop
+	})
+	if err != stop {
+		t.Errorf("PnsErrorDetails() error = %v, want %v", err, stop)
+	}
+
+	for _, blob := range []string{"malformed.json", "missing.json"} {
+		err = hub.PnsErrorDetails(context.Background(), &NotificationDetails{PnsErrorDetailsURI: server.URL + "/pnserrors/" + blob}, collect)
+		if err == nil {
+			t.Errorf("PnsErrorDetails(%s) error = nil, want error", blob)
+		}
+	}
+
+	if err = hub.PnsErrorDetails(context.Background(), &NotificationDetails{}, collect); err != nil {
+		t.Errorf("PnsErrorDetails() without URI error = %v, want nil", err)
+	}
+	hub.SetHTTPClient(utils.NewHubHTTPClientWithOptions(&utils.HubHTTPClientOptions{MaxResponseBodySize: 16}))
+	err = hub.PnsErrorDetails(context.Background(), &NotificationDetails{PnsErrorDetailsURI: server.URL + "/pnserrors/ok.json"}, collect)
+	var sizeErr *utils.ResponseTooLargeError
+	if !errors.As(err, &sizeErr) || !strings.Contains(err.Error(), "PnsErrorDetails: blob is larger than the 16 bytes response limit") {
+		t.Errorf("PnsErrorDetails() error = %v, want response limit error", err)
+	}
+}
+
+func TestNotificationDetails(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if want := "/testhub/messages/3288835312934927344-986564390439048203-1"; r.URL.Path != want {
+			t.Errorf("NotificationDetails() path = %s, want %s", r.URL.Path, want)
+		}
+		if got := r.URL.Query().Get(apiVersionParam); got != telemetryAPIVersionValue {
+			t.Errorf("NotificationDetails() api-version = %s, want %s", got, telemetryAPIVersionValue)
+		}
+		data, _ := ioutil.ReadFile("./fixtures/notificationDetails.xml")
+		_, _ = w.Write(data)
+	}))
+	defer server.Close()
+
+	hub := newNotificationHub("Endpoint=sb://testhub-ns.servicebus.windows.net/;SharedAccessKeyName=name;SharedAccessKey=key", "testhub")
+	serverURL, _ := url.Parse(server.URL)
+	hub.HubURL.Scheme = serverURL.Scheme
+	hub.HubURL.Host = serverURL.Host
+
+	details, _, err := hub.NotificationDetails(context.Background(), "3288835312934927344-986564390439048203-1")
+	if err != nil {
+		t.Fatalf("NotificationDetails() error = %v", err)
+	}
+
+	var (
+		enqueueTime = time.Date(2020, 4, 20, 9, 10, 11, 123456700, time.UTC)
+		endTime     = time.Date(2020, 4, 20, 9, 10, 14, 0, time.UTC)
+	)
+	if details.EnqueueTime == nil || !details.EnqueueTime.Equal(enqueueTime) {
+		t.Errorf("EnqueueTime = %v, want %v", details.EnqueueTime, enqueueTime)
+	}
+	if details.EndTime == nil || !details.EndTime.Equal(endTime) {
+		t.Errorf("EndTime = %v, want %v", details.EndTime, endTime)
+	}
+	if details.EnqueueTimeString != nil || details.TargetPlatformsString != nil {
+		t.Errorf("raw strings not cleared: %v, %v", details.EnqueueTimeString, details.TargetPlatformsString)
+	}
+	if want := []TargetPlatform{ApplePlatform, GcmPlatform}; !reflect.DeepEqual(details.TargetPlatforms, want) {
+		t.Errorf("TargetPlatforms = %v, want %v", details.TargetPlatforms, want)
+	}
+	if got := len(details.PlatformOutcomes()); got != 2 {
+		t.Errorf("len(PlatformOutcomes()) = %d, want 2", got)
+	}
+	if got := details.Total(); got != 4 {
+		t.Errorf("Total() = %d, want 4", got)
+	}
+	if got := details.Count(ExpiredChannel); got != 1 {
+		t.Errorf("Count(ExpiredChannel) = %d, want 1", got)
+	}
+	if got := details.SuccessRate(); got != 0.5 {
+		t.Errorf("SuccessRate() = %v, want 0.5", got)
+	}
+	if !details.IsTerminal() {
+		t.Errorf("IsTerminal() = false, want true")
+	}
+	if got := (&NotificationDetails{}).SuccessRate(); got != 0 {
+		t.Errorf("empty SuccessRate() = %v, want 0", got)
+	}
+}
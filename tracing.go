@@ -0,0 +1,106 @@
+package notificationhubs
+
+import (
+	"context"
+	"encoding/hex"
+	"net/http"
+)
+
+// Span attributes set on every hub request
+const (
+	AttrOperation           = "notificationhubs.operation"
+	AttrHubPath             = "notificationhubs.hub_path"
+	AttrFormat              = "notificationhubs.format"
+	AttrTagExpressionLength = "notificationhubs.tag_expression_length"
+	AttrBatchSize           = "notificationhubs.batch_size"
+	AttrTrackingID          = "notificationhubs.tracking_id"
+	AttrHTTPStatusCode      = "http.status_code"
+)
+
+const (
+	traceParentHeader = "traceparent"
+	traceStateHeader  = "tracestate"
+	trackingIDHeader  = "TrackingId"
+)
+
+type (
+	// Tracer starts a span for every hub request, adapting the tracing library of the application
+	Tracer interface {
+		// Start starts a span named after the NotificationHub method and returns the context carrying it
+		Start(ctx context.Context, operation string) (context.Context, Span)
+	}
+
+	// Span is a traced hub request
+	Span interface {
+		SetAttribute(key string, value interface{})
+		RecordError(err error)
+		End()
+		// TraceContext returns the W3C traceparent and tracestate header values propagated to the hub,
+		// an empty traceparent is not propagated
+		TraceContext() (traceParent, traceState string)
+	}
+
+	// tracingContextKey is the type of the context keys of the tracing attributes
+	tracingContextKey int
+)
+
+const batchSizeKey tracingContextKey = iota
+
+// SetTracer traces every hub request with the tracer
+func (h *NotificationHub) SetTracer(t Tracer) {
+	h.tracer = t
+}
+
+// FormatTraceParent formats a W3C traceparent header value
+func FormatTraceParent(traceID [16]byte, spanID [8]byte, sampled bool) string {
+	flags := "00"
+	if sampled {
+		flags = "01"
+	}
+	return "00-" + hex.EncodeToString(traceID[:]) + "-" + hex.EncodeToString(spanID[:]) + "-" + flags
+}
+
+// withBatchSize returns a context carrying the number of devices of a batch request
+func withBatchSize(ctx context.Context, size int) context.Context {
+	return context.WithValue(ctx, batchSizeKey, size)
+}
+
+// startSpan starts the span of a hub request and adds the trace context to the headers
+func (h *NotificationHub) startSpan(ctx context.Context, operation string, headers Headers) (context.Context, Span) {
+	ctx, span := h.tracer.Start(ctx, operation)
+	span.SetAttribute(AttrOperation, operation)
+	span.SetAttribute(AttrHubPath, h.HubURL.Path)
+	if format, ok := headers["ServiceBusNotification-Format"]; ok {
+		span.SetAttribute(AttrFormat, format)
+	}
+	if tags, ok := headers["ServiceBusNotification-Tags"]; ok {
+		span.SetAttribute(AttrTagExpressionLength, len(tags))
+	}
+	if size, ok := ctx.Value(batchSizeKey).(int); ok {
+		span.SetAttribute(AttrBatchSize, size)
+	}
+
+	if traceParent, traceState := span.TraceContext(); traceParent != "" {
+		headers[traceParentHeader] = traceParent
+		if traceState != "" {
+			headers[traceStateHeader] = traceState
+		}
+	}
+	return ctx, span
+}
+
+// endSpan records the outcome of a hub request and ends its span
+func endSpan(span Span, response *http.Response, err error) {
+	if statusCode := responseStatusCode(response, err); statusCode != 0 {
+		span.SetAttribute(AttrHTTPStatusCode, statusCode)
+	}
+	if response != nil {
+		if trackingID := response.Header.Get(trackingIDHeader); trackingID != "" {
+			span.SetAttribute(AttrTrackingID, trackingID)
+		}
+	}
+	if err != nil {
+		span.RecordError(err)
+	}
+	span.End()
+}
@@ -0,0 +1,146 @@
+package notificationhubs_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	. "github.com/daresaydigital/azure-notificationhubs-go"
+	"github.com/daresaydigital/azure-notificationhubs-go/utils"
+)
+
+type (
+	mockTracer struct {
+		spans []*mockSpan
+	}
+
+	mockSpan struct {
+		operation  string
+		attributes map[string]interface{}
+		err        error
+		ended      bool
+	}
+)
+
+func (t *mockTracer) Start(ctx context.Context, operation string) (context.Context, Span) {
+	span := &mockSpan{operation: operation, attributes: map[string]interface{}{}}
+	t.spans = append(t.spans, span)
+	return ctx, span
+}
+
+func (s *mockSpan) SetAttribute(key string, value interface{}) { s.attributes[key] = value }
+func (s *mockSpan) RecordError(err error)                      { s.err = err }
+func (s *mockSpan) End()                                       { s.ended = true }
+
+func (s *mockSpan) TraceContext() (string, string) {
+	return FormatTraceParent([16]byte{1}, [8]byte{2}, true), "vendor=value"
+}
+
+func Test_SetTracer(t *testing.T) {
+	var (
+		nhub, mockClient = initTestItems()
+		tracer           = &mockTracer{}
+		notification, _  = NewNotification(AppleFormat, []byte("{}"))
+		tags             = "tag1 || tag2"
+	)
+	nhub.SetTracer(tracer)
+
+	mockClient.execFunc = func(req *http.Request) ([]byte, *http.Response, error) {
+		if want := "00-01000000000000000000000000000000-0200000000000000-01"; req.Header.Get("traceparent") != want {
+			t.Errorf(errfmt, "traceparent", want, req.Header.Get("traceparent"))
+		}
+		if req.Header.Get("tracestate") != "vendor=value" {
+			t.Errorf(errfmt, "tracestate", "vendor=value", req.Header.Get("tracestate"))
+		}
+		if req.URL.Path == "/testhub/messages/$batch" {
+			return nil, nil, &utils.HTTPError{StatusCode: http.StatusBadRequest}
+		}
+		return nil, &http.Response{
+			StatusCode: http.StatusCreated,
+			Header: http.Header{
+				"Location":   []string{"https://testhub-ns.servicebus.windows.net/testhub/messages/1?api-version=2016-07"},
+				"Trackingid": []string{"tracking-1"},
+			},
+		}, nil
+	}
+
+	_, _, _ = nhub.Send(context.Background(), notification, &tags)
+	_, _, _ = nhub.SendDirectBatch(context.Background(), notification, "handle1", "handle2")
+
+	if len(tracer.spans) != 2 {
+		t.Fatalf(errfmt, "spans", 2, len(tracer.spans))
+	}
+
+	send := tracer.spans[0]
+	expected := map[string]interface{}{
+		AttrOperation:           "Send",
+		AttrHubPath:             "testhub",
+		AttrFormat:              "apple",
+		AttrTagExpressionLength: len(tags),
+		AttrHTTPStatusCode:      http.StatusCreated,
+		AttrTrackingID:          "tracking-1",
+	}
+	for key, value := range expected {
+		if send.attributes[key] != value {
+			t.Errorf(errfmt, "Send span "+key, value, send.attributes[key])
+		}
+	}
+	if send.operation != "Send" || !send.ended || send.err != nil {
+		t.Errorf(errfmt, "Send span", "ended without error", send)
+	}
+
+	batch := tracer.spans[1]
+	if batch.attributes[AttrBatchSize] != 2 || batch.attributes[AttrHTTPStatusCode] != http.StatusBadRequest {
+		t.Errorf(errfmt, "SendDirectBatch span attributes", "batch size 2 and status 400", batch.attributes)
+	}
+	if batch.err == nil || !batch.ended {
+		t.Errorf(errfmt, "SendDirectBatch span", "ended with error", batch)
+	}
+}
+
+func Test_SetTracerBlobRequests(t *testing.T) {
+	var (
+		nhub, mockClient = initTestItems()
+		tracer           = &mockTracer{}
+		blobURL, _       = url.Parse("https://testhubblob.blob.core.windows.net/feedback/feedback.json?sig=signature")
+	)
+	nhub.SetTracer(tracer)
+
+	mockClient.execFunc = func(req *http.Request) ([]byte, *http.Response, error) {
+		if req.Header.Get("Authorization") != "" {
+			t.Errorf(errfmt, "blob Authorization header", "", req.Header.Get("Authorization"))
+		}
+		if req.Header.Get("traceparent") == "" {
+			t.Errorf(errfmt, "traceparent", "trace context", "")
+		}
+		fixture := "./fixtures/pnsErrorDetails.json"
+		if strings.Contains(req.URL.Path, "/feedback/") {
+			fixture = "./fixtures/feedback.json"
+		}
+		data, err := ioutil.ReadFile(fixture)
+		return data, &http.Response{StatusCode: http.StatusOK}, err
+	}
+
+	err := nhub.PnsErrorDetails(context.Background(), &NotificationDetails{
+		PnsErrorDetailsURI: "https://testhubblob.blob.core.windows.net/pnserrors/1.json?sig=signature",
+	}, func(PnsErrorDetail) error { return nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = nhub.FeedbackRecords(context.Background(), FeedbackBlob{URL: blobURL}, func(FeedbackRecord) error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(tracer.spans) != 2 {
+		t.Fatalf(errfmt, "spans", 2, len(tracer.spans))
+	}
+	for i, operation := range []string{"PnsErrorDetails", "FeedbackRecords"} {
+		span := tracer.spans[i]
+		if span.operation != operation || !span.ended || span.err != nil || span.attributes[AttrHTTPStatusCode] != http.StatusOK {
+			t.Errorf(errfmt, operation+" span", "ended with status 200", span)
+		}
+	}
+}
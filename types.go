@@ -64,6 +64,7 @@ type (
 		Template       string     `xml:"BodyTemplate"   json:"template,omitempty"`
 		RegistrationID string     `xml:"RegistrationId" json:"registrationID,omitempty"`
 		Tags           []string   `xml:"-"              json:"tags,omitempty"`
+		TemplateName   string     `xml:"TemplateName"   json:"templateName,omitempty"`
 
 		DeviceToken          *string `xml:"DeviceToken"       json:"-"`
 		ExpirationTimeString *string `xml:"ExpirationTime"    json:"-"`
@@ -74,6 +75,7 @@ type (
 	// Installation is a device installation in the hub
 	Installation struct {
 		InstallationID     string                               `json:"installationId,omitempty"`
+		UserID             string                               `json:"userId,omitempty"`
 		LastActiveOn       *time.Time                           `json:"lastActiveOn,omitempty"`
 		ExpirationTime     *time.Time                           `json:"expirationTime,omitempty"`
 		LastUpdate         *time.Time                           `json:"lastUpdate,omitempty"`
@@ -107,17 +109,53 @@ type (
 		Value string               `json:"value,omitempty"`
 	}
 
+	// AudienceEstimate is the number of known devices matching a tag expression
+	AudienceEstimate struct {
+		Scanned       int                          `json:"scanned"`
+		Matched       int                          `json:"matched"`
+		Registrations map[TargetPlatform]int       `json:"registrations,omitempty"`
+		Installations map[InstallationPlatform]int `json:"installations,omitempty"`
+	}
+
 	// NotificationDetails is the detailed information about a sent or scheduled message
 	NotificationDetails struct {
-		ID                string                `xml:"NotificationId"`
-		State             NotificationState     `xml:"State"`
-		EnqueueTime       string                `xml:"EnqueueTime"`
-		StartTime         string                `xml:"StartTime"`
-		EndTime           string                `xml:"EndTime"`
-		Body              string                `xml:"NotificationBody"`
-		TargetPlatforms   string                `xml:"TargetPlatforms"`
-		ApnsOutcomeCounts *NotificationOutcomes `xml:"ApnsOutcomeCounts"`
-		GcmOutcomeCounts  *NotificationOutcomes `xml:"GcmOutcomeCounts"`
+		ID                   string                `xml:"NotificationId"`
+		Location             string                `xml:"Location"`
+		State                NotificationState     `xml:"State"`
+		EnqueueTime          *time.Time            `xml:"-"`
+		StartTime            *time.Time            `xml:"-"`
+		EndTime              *time.Time            `xml:"-"`
+		Body                 string                `xml:"NotificationBody"`
+		TargetPlatforms      []TargetPlatform      `xml:"-"`
+		ApnsOutcomeCounts    *NotificationOutcomes `xml:"ApnsOutcomeCounts"`
+		WnsOutcomeCounts     *NotificationOutcomes `xml:"WnsOutcomeCounts"`
+		MpnsOutcomeCounts    *NotificationOutcomes `xml:"MpnsOutcomeCounts"`
+		AdmOutcomeCounts     *NotificationOutcomes `xml:"AdmOutcomeCounts"`
+		BaiduOutcomeCounts   *NotificationOutcomes `xml:"BaiduOutcomeCounts"`
+		GcmOutcomeCounts     *NotificationOutcomes `xml:"GcmOutcomeCounts"`
+		FcmV1OutcomeCounts   *NotificationOutcomes `xml:"FcmV1OutcomeCounts"`
+		XiaomiOutcomeCounts  *NotificationOutcomes `xml:"XiaomiOutcomeCounts"`
+		BrowserOutcomeCounts *NotificationOutcomes `xml:"BrowserOutcomeCounts"`
+		PnsErrorDetailsURI   string                `xml:"PnsErrorDetailsUri"`
+
+		EnqueueTimeString     *string `xml:"EnqueueTime"`
+		StartTimeString       *string `xml:"StartTime"`
+		EndTimeString         *string `xml:"EndTime"`
+		TargetPlatformsString *string `xml:"TargetPlatforms"`
+	}
+
+	// PnsErrorDetail is the failure to deliver a notification to one device handle
+	PnsErrorDetail struct {
+		Platform  InstallationPlatform    `json:"platform"`
+		Handle    string                  `json:"pnsHandle"`
+		Outcome   NotificationOutcomeName `json:"outcome"`
+		Timestamp *time.Time              `json:"timestamp,omitempty"`
+	}
+
+	// BatchOutcome is the outcome of a direct batch send for every device handle
+	BatchOutcome struct {
+		Details  *NotificationDetails
+		Outcomes map[string]NotificationOutcomeName
 	}
 
 	// NotificationTelemetry is the id of a sent or scheduled message
@@ -153,4 +191,10 @@ type (
 
 	// InstallationChangeOp is the installation change operation
 	InstallationChangeOp string
+
+	// NotificationHubJobType is the type of an import or export job
+	NotificationHubJobType string
+
+	// NotificationHubJobStatus is the status of an import or export job
+	NotificationHubJobStatus string
 )
@@ -0,0 +1,279 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultFailureRatio     = 0.5
+	defaultMinRequests      = 10
+	defaultBreakerWindow    = time.Minute
+	defaultBreakerCooldown  = 30 * time.Second
+	defaultHalfOpenRequests = 1
+)
+
+// Endpoint classes of EndpointClass, each with its own breaker by default
+const (
+	SendEndpoint          = "send"
+	TelemetryEndpoint     = "telemetry"
+	InstallationsEndpoint = "installations"
+	RegistrationsEndpoint = "registrations"
+	JobsEndpoint          = "jobs"
+	OtherEndpoint         = "other"
+)
+
+// CircuitClosed and the other states of a circuit breaker
+const (
+	CircuitClosed CircuitState = iota
+	CircuitOpen
+	CircuitHalfOpen
+)
+
+// ErrCircuitOpen is returned without calling the hub while the breaker of the endpoint class is open
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+type (
+	// CircuitState is the state of a circuit breaker
+	CircuitState int
+
+	// CircuitBreakerOptions configures a CircuitBreakerClient
+	CircuitBreakerOptions struct {
+		// FailureRatio opens the breaker when reached by the failed requests of a window, defaults to 0.5
+		FailureRatio float64
+		// MinRequests is the number of requests of a window before the breaker can open, defaults to 10
+		MinRequests int
+		// Window is the period over which failures are counted, defaults to one minute
+		Window time.Duration
+		// Cooldown is the time the breaker stays open before allowing trial requests, defaults to 30 seconds
+		Cooldown time.Duration
+		// HalfOpenRequests is the number of successful trial requests closing the breaker, defaults to 1
+		HalfOpenRequests int
+		// IsFailure identifies failed requests, defaults to transport errors, timeouts, 429 and 5xx responses
+		IsFailure func(resp *http.Response, err error) bool
+		// Classify returns the endpoint class of a request, defaults to EndpointClass
+		Classify func(req *http.Request) string
+		// OnStateChange is called when the breaker of an endpoint class changes state, or nil
+		OnStateChange func(class string, from, to CircuitState)
+	}
+
+	// CircuitBreakerClient is an HTTPClient failing fast while the hub is degraded
+	// Each endpoint class has its own breaker, so failing sends do not block installation reads
+	CircuitBreakerClient struct {
+		next HTTPClient
+		opts CircuitBreakerOptions
+
+		mu       sync.Mutex
+		breakers map[string]*circuitBreaker
+	}
+
+	// circuitBreaker is the state of one endpoint class
+	circuitBreaker struct {
+		state       CircuitState
+		windowStart time.Time
+		requests    int
+		failures    int
+		openedAt    time.Time
+		halfOpens   int
+		trials      int
+		successes   int
+	}
+)
+
+// NewCircuitBreakerClient wraps an HTTPClient with circuit breakers
+func NewCircuitBreakerClient(next HTTPClient, opts *CircuitBreakerOptions) *CircuitBreakerClient {
+	c := &CircuitBreakerClient{next: next, breakers: map[string]*circuitBreaker{}}
+	if opts != nil {
+		c.opts = *opts
+	}
+	if c.opts.FailureRatio <= 0 {
+		c.opts.FailureRatio = defaultFailureRatio
+	}
+	if c.opts.MinRequests < 1 {
+		c.opts.MinRequests = defaultMinRequests
+	}
+	if c.opts.Window <= 0 {
+		c.opts.Window = defaultBreakerWindow
+	}
+	if c.opts.Cooldown <= 0 {
+		c.opts.Cooldown = defaultBreakerCooldown
+	}
+	if c.opts.HalfOpenRequests < 1 {
+		c.opts.HalfOpenRequests = defaultHalfOpenRequests
+	}
+	if c.opts.IsFailure == nil {
+		c.opts.IsFailure = isCircuitFailure
+	}
+	if c.opts.Classify == nil {
+		c.opts.Classify = EndpointClass
+	}
+	return c
+}
+
+// Exec executes the request unless the breaker of its endpoint class is open
+func (c *CircuitBreakerClient) Exec(req *http.Request) ([]byte, *http.Response, error) {
+	class := c.opts.Classify(req)
+	allowed, trial := c.allow(class)
+	if !allowed {
+		return nil, nil, ErrCircuitOpen
+	}
+	b, resp, err := c.next.Exec(req)
+	c.record(class, trial, c.opts.IsFailure(resp, err), errors.Is(err, context.Canceled))
+	return b, resp, err
+}
+
+// State returns the state of the breaker of an endpoint class
+func (c *CircuitBreakerClient) State(class string) CircuitState {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if b, ok := c.breakers[class]; ok {
+		if b.state == CircuitOpen && time.Since(b.openedAt) >= c.opts.Cooldown {
+			return CircuitHalfOpen
+		}
+		return b.state
+	}
+	return CircuitClosed
+}
+
+// allow identifies whether a request may be executed, moving an open breaker to half-open after the cool-down
+// A request admitted as a trial of the half-open breaker gets the number of that half-open period, else 0
+func (c *CircuitBreakerClient) allow(class string) (allowed bool, trial int) {
+	c.mu.Lock()
+	b := c.breaker(class)
+	from := b.state
+	allowed = true
+	switch b.state {
+	case CircuitOpen:
+		if time.Since(b.openedAt) < c.opts.Cooldown {
+			allowed = false
+			break
+		}
+		b.halfOpens++
+		b.state, b.trials, b.successes = CircuitHalfOpen, 1, 0
+		trial = b.halfOpens
+	case CircuitHalfOpen:
+		if b.trials >= c.opts.HalfOpenRequests {
+			allowed = false
+			break
+		}
+		b.trials++
+		trial = b.halfOpens
+	}
+	to := b.state
+	c.mu.Unlock()
+
+	c.changed(class, from, to)
+	return
+}
+
+// record counts the outcome of an executed request and changes the state accordingly
+// Only the trials of the current half-open period decide it, a canceled trial releases its slot
+func (c *CircuitBreakerClient) record(class string, trial int, failed, canceled bool) {
+	c.mu.Lock()
+	b := c.breaker(class)
+	from := b.state
+	now := time.Now()
+	switch b.state {
+	case CircuitHalfOpen:
+		switch {
+		case trial == 0 || trial != b.halfOpens:
+		case canceled:
+			b.trials--
+		case failed:
+			b.open(now)
+		default:
+			if b.successes++; b.successes >= c.opts.HalfOpenRequests {
+				b.close(now)
+			}
+		}
+	case CircuitClosed:
+		if now.Sub(b.windowStart) >= c.opts.Window {
+			b.windowStart, b.requests, b.failures = now, 0, 0
+		}
+		b.requests++
+		if failed {
+			b.failures++
+		}
+		if b.requests >= c.opts.MinRequests && float64(b.failures)/float64(b.requests) >= c.opts.FailureRatio {
+			b.open(now)
+		}
+	}
+	to := b.state
+	c.mu.Unlock()
+
+	c.changed(class, from, to)
+}
+
+// breaker returns the breaker of an endpoint class, the caller must hold the lock
+func (c *CircuitBreakerClient) breaker(class string) *circuitBreaker {
+	b, ok := c.breakers[class]
+	if !ok {
+		b = &circuitBreaker{windowStart: time.Now()}
+		c.breakers[class] = b
+	}
+	return b
+}
+
+// changed calls the state change callback, outside of the lock
+func (c *CircuitBreakerClient) changed(class string, from, to CircuitState) {
+	if from != to && c.opts.OnStateChange != nil {
+		c.opts.OnStateChange(class, from, to)
+	}
+}
+
+func (b *circuitBreaker) open(now time.Time) {
+	b.state, b.openedAt = CircuitOpen, now
+}
+
+func (b *circuitBreaker) close(now time.Time) {
+	b.state, b.windowStart, b.requests, b.failures = CircuitClosed, now, 0, 0
+}
+
+// String returns the name of the state
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	}
+	return "unknown"
+}
+
+// EndpointClass classifies a hub request by the resource it targets
+func EndpointClass(req *http.Request) string {
+	path := req.URL.Path
+	switch {
+	case strings.Contains(path, "/messages"):
+		if req.Method == http.MethodPost {
+			return SendEndpoint
+		}
+		return TelemetryEndpoint
+	case strings.Contains(path, "/installations"):
+		return InstallationsEndpoint
+	case strings.Contains(path, "/registrations"):
+		return RegistrationsEndpoint
+	case strings.Contains(path, "/jobs"):
+		return JobsEndpoint
+	}
+	return OtherEndpoint
+}
+
+// isCircuitFailure identifies transport errors, timeouts, 429 and 5xx responses
+// Canceled requests and other responses do not indicate a degraded hub
+func isCircuitFailure(resp *http.Response, err error) bool {
+	if err == nil {
+		return false
+	}
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.StatusCode == http.StatusTooManyRequests || httpErr.StatusCode >= http.StatusInternalServerError
+	}
+	return !errors.Is(err, context.Canceled)
+}
@@ -0,0 +1,184 @@
+package utils
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type mockHTTPClient struct {
+	status map[string]int
+	calls  int
+}
+
+func (m *mockHTTPClient) Exec(req *http.Request) ([]byte, *http.Response, error) {
+	m.calls++
+	if code := m.status[EndpointClass(req)]; code != 0 {
+		return nil, &http.Response{StatusCode: code}, &HTTPError{StatusCode: code}
+	}
+	return nil, &http.Response{StatusCode: http.StatusOK}, nil
+}
+
+func newTestRequest(method, path string) *http.Request {
+	return &http.Request{Method: method, URL: &url.URL{Scheme: "https", Host: "testhub-ns.servicebus.windows.net", Path: path}}
+}
+
+func TestCircuitBreakerClient(t *testing.T) {
+	var (
+		mock        = &mockHTTPClient{status: map[string]int{SendEndpoint: http.StatusServiceUnavailable}}
+		transitions []string
+		client      = NewCircuitBreakerClient(mock, &CircuitBreakerOptions{
+			MinRequests: 4,
+			Cooldown:    20 * time.Millisecond,
+			OnStateChange: func(class string, from, to CircuitState) {
+				transitions = append(transitions, class+": "+from.String()+" -> "+to.String())
+			},
+		})
+		send = newTestRequest(http.MethodPost, "/testhub/messages")
+		read = newTestRequest(http.MethodGet, "/testhub/installations/installation-1")
+	)
+
+	for i := 0; i < 4; i++ {
+		if _, _, err := client.Exec(send); err == ErrCircuitOpen {
+			t.Fatalf("unexpected open circuit after %d requests", i)
+		}
+	}
+	if _, _, err := client.Exec(send); err != ErrCircuitOpen {
+		t.Errorf("expected ErrCircuitOpen, got %v", err)
+	}
+	if mock.calls != 4 {
+		t.Errorf("expected 4 calls, got %d", mock.calls)
+	}
+	if _, _, err := client.Exec(read); err != nil {
+		t.Errorf("expected installation read to pass, got %v", err)
+	}
+	if state := client.State(InstallationsEndpoint); state != CircuitClosed {
+		t.Errorf("expected closed installations breaker, got %s", state)
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	if state := client.State(SendEndpoint); state != CircuitHalfOpen {
+		t.Errorf("expected half-open send breaker, got %s", state)
+	}
+	if _, _, err := client.Exec(send); err == ErrCircuitOpen || err == nil {
+		t.Errorf("expected failed trial request, got %v", err)
+	}
+	if state := client.State(SendEndpoint); state != CircuitOpen {
+		t.Errorf("expected open send breaker, got %s", state)
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	delete(mock.status, SendEndpoint)
+	if _, _, err := client.Exec(send); err != nil {
+		t.Errorf("expected successful trial request, got %v", err)
+	}
+	if state := client.State(SendEndpoint); state != CircuitClosed {
+		t.Errorf("expected closed send breaker, got %s", state)
+	}
+
+	expected := []string{
+		"send: closed -> open",
+		"send: open -> half-open",
+		"send: half-open -> open",
+		"send: open -> half-open",
+		"send: half-open -> closed",
+	}
+	if !reflect.DeepEqual(transitions, expected) {
+		t.Errorf("expected transitions %v, got %v", expected, transitions)
+	}
+}
+
+func TestCircuitBreakerClientIgnoresClientErrors(t *testing.T) {
+	var (
+		mock   = &mockHTTPClient{status: map[string]int{RegistrationsEndpoint: http.StatusBadRequest}}
+		client = NewCircuitBreakerClient(mock, &CircuitBreakerOptions{MinRequests: 2})
+		req    = newTestRequest(http.MethodPost, "/testhub/registrations")
+	)
+
+	for i := 0; i < 5; i++ {
+		if _, _, err := client.Exec(req); err == ErrCircuitOpen {
+			t.Fatal("unexpected open circuit for client errors")
+		}
+	}
+}
+
+func TestEndpointClass(t *testing.T) {
+	testCases := []struct {
+		method, path, class string
+	}{
+		{http.MethodPost, "/testhub/messages/$batch", SendEndpoint},
+		{http.MethodGet, "/testhub/messages/1", TelemetryEndpoint},
+		{http.MethodPut, "/testhub/installations/1", InstallationsEndpoint},
+		{http.MethodGet, "/testhub/registrations", RegistrationsEndpoint},
+		{http.MethodPost, "/testhub/jobs", JobsEndpoint},
+		{http.MethodGet, "/testhub", OtherEndpoint},
+	}
+	for _, testCase := range testCases {
+		if class := EndpointClass(newTestRequest(testCase.method, testCase.path)); class != testCase.class {
+			t.Errorf("expected %s %s to be %s, got %s", testCase.method, testCase.path, testCase.class, class)
+		}
+	}
+}
+
+// funcHTTPClient executes requests with a function
+type funcHTTPClient func(req *http.Request) ([]byte, *http.Response, error)
+
+func (f funcHTTPClient) Exec(req *http.Request) ([]byte, *http.Response, error) {
+	return f(req)
+}
+
+func TestCircuitBreakerClientTrials(t *testing.T) {
+	var (
+		started = make(chan string)
+		results = map[string]chan error{"slow": make(chan error), "trial": make(chan error), "retrial": make(chan error)}
+		client  = NewCircuitBreakerClient(funcHTTPClient(func(req *http.Request) ([]byte, *http.Response, error) {
+			name := req.Header.Get("X-Test")
+			started <- name
+			return nil, nil, <-results[name]
+		}), nil)
+	)
+	exec := func(name string) <-chan error {
+		req := newTestRequest(http.MethodPost, "/testhub/messages")
+		req.Header = http.Header{"X-Test": []string{name}}
+		done := make(chan error, 1)
+		go func() {
+			_, _, err := client.Exec(req)
+			done <- err
+		}()
+		<-started
+		return done
+	}
+
+	// a request admitted while closed finishes after the breaker went half-open
+	slow := exec("slow")
+	client.mu.Lock()
+	client.breaker(SendEndpoint).open(time.Now().Add(-time.Hour))
+	client.mu.Unlock()
+	trial := exec("trial")
+	results["slow"] <- nil
+	<-slow
+	if state := client.State(SendEndpoint); state != CircuitHalfOpen {
+		t.Errorf("expected a request admitted before half-open to be no trial, got %s", state)
+	}
+
+	// a canceled trial releases its slot without closing the breaker
+	results["trial"] <- context.Canceled
+	if err := <-trial; err != context.Canceled {
+		t.Fatalf("expected canceled trial, got %v", err)
+	}
+	if state := client.State(SendEndpoint); state != CircuitHalfOpen {
+		t.Errorf("expected half-open breaker after a canceled trial, got %s", state)
+	}
+
+	retrial := exec("retrial")
+	results["retrial"] <- nil
+	if err := <-retrial; err != nil {
+		t.Fatalf("expected successful trial, got %v", err)
+	}
+	if state := client.State(SendEndpoint); state != CircuitClosed {
+		t.Errorf("expected closed breaker, got %s", state)
+	}
+}
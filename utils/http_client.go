@@ -1,9 +1,27 @@
 package utils
 
 import (
+	"crypto/tls"
+	"crypto/x509"
 	"fmt"
+	"io"
 	"io/ioutil"
+	"net"
 	"net/http"
+	"net/url"
+	"time"
+)
+
+const (
+	defaultRequestTimeout      = 30 * time.Second
+	defaultDialTimeout         = 10 * time.Second
+	defaultKeepAlive           = 30 * time.Second
+	defaultMaxIdleConns        = 100
+	defaultMaxIdleConnsPerHost = 100
+	defaultMaxConnsPerHost     = 100
+	defaultIdleConnTimeout     = 90 * time.Second
+	defaultTLSHandshakeTimeout = 10 * time.Second
+	defaultMaxResponseBodySize = 64 << 20
 )
 
 type (
@@ -14,25 +32,113 @@ type (
 
 	// HubHTTPClient is the internal HTTPClient
 	HubHTTPClient struct {
-		httpClient *http.Client
+		httpClient          *http.Client
+		maxResponseBodySize int64
+	}
+
+	// HubHTTPClientOptions tunes the HubHTTPClient, zero values are the defaults
+	HubHTTPClientOptions struct {
+		// Timeout is the time limit of a request including reading the response, defaults to 30 seconds
+		Timeout time.Duration
+		// MaxIdleConnsPerHost is the number of keep-alive connections pooled per host, defaults to 100
+		MaxIdleConnsPerHost int
+		// MaxConnsPerHost limits the connections per host, defaults to 100
+		MaxConnsPerHost int
+		// IdleConnTimeout closes pooled connections idle for longer, defaults to 90 seconds
+		IdleConnTimeout time.Duration
+		// TLSHandshakeTimeout is the time limit of a TLS handshake, defaults to 10 seconds
+		TLSHandshakeTimeout time.Duration
+		// Proxy selects the proxy of a request, defaults to the environment proxy settings
+		Proxy func(*http.Request) (*url.URL, error)
+		// RootCAs are the trusted certificate authorities, defaults to the system roots
+		RootCAs *x509.CertPool
+		// DisableHTTP2 uses HTTP/1.1 only
+		DisableHTTP2 bool
+		// MaxResponseBodySize is the largest response body read, defaults to 64 MiB
+		MaxResponseBodySize int64
+	}
+
+	// ResponseTooLargeError is returned by HubHTTPClient for a response body larger than MaxResponseBodySize
+	ResponseTooLargeError struct {
+		Limit int64
+	}
+
+	// HTTPError is returned by HubHTTPClient for an unexpected response status code
+	HTTPError struct {
+		StatusCode int
+		Body       []byte
 	}
 )
 
 // NewHubHTTPClient is creating the default client
 func NewHubHTTPClient() HTTPClient {
+	return NewHubHTTPClientWithOptions(nil)
+}
+
+// NewHubHTTPClientWithOptions is creating a client with a tuned transport
+func NewHubHTTPClientWithOptions(opts *HubHTTPClientOptions) HTTPClient {
+	var o HubHTTPClientOptions
+	if opts != nil {
+		o = *opts
+	}
+	if o.Timeout <= 0 {
+		o.Timeout = defaultRequestTimeout
+	}
+	if o.MaxIdleConnsPerHost <= 0 {
+		o.MaxIdleConnsPerHost = defaultMaxIdleConnsPerHost
+	}
+	if o.MaxConnsPerHost <= 0 {
+		o.MaxConnsPerHost = defaultMaxConnsPerHost
+	}
+	if o.IdleConnTimeout <= 0 {
+		o.IdleConnTimeout = defaultIdleConnTimeout
+	}
+	if o.TLSHandshakeTimeout <= 0 {
+		o.TLSHandshakeTimeout = defaultTLSHandshakeTimeout
+	}
+	if o.Proxy == nil {
+		o.Proxy = http.ProxyFromEnvironment
+	}
+	if o.MaxResponseBodySize <= 0 {
+		o.MaxResponseBodySize = defaultMaxResponseBodySize
+	}
+
+	transport := &http.Transport{
+		Proxy: o.Proxy,
+		DialContext: (&net.Dialer{
+			Timeout:   defaultDialTimeout,
+			KeepAlive: defaultKeepAlive,
+		}).DialContext,
+		TLSClientConfig:     &tls.Config{RootCAs: o.RootCAs},
+		ForceAttemptHTTP2:   !o.DisableHTTP2,
+		MaxIdleConns:        defaultMaxIdleConns,
+		MaxIdleConnsPerHost: o.MaxIdleConnsPerHost,
+		MaxConnsPerHost:     o.MaxConnsPerHost,
+		IdleConnTimeout:     o.IdleConnTimeout,
+		TLSHandshakeTimeout: o.TLSHandshakeTimeout,
+	}
+	if o.DisableHTTP2 {
+		transport.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+	}
+
 	return HubHTTPClient{
-		httpClient: &http.Client{},
+		httpClient: &http.Client{
+			Transport: transport,
+			Timeout:   o.Timeout,
+		},
+		maxResponseBodySize: o.MaxResponseBodySize,
 	}
 }
 
 // Exec executes notification hub http request and handles the response
 func (hc HubHTTPClient) Exec(req *http.Request) ([]byte, *http.Response, error) {
-	return handleResponse(hc.httpClient.Do(req))
+	resp, err := hc.httpClient.Do(req)
+	return handleResponse(resp, err, hc.maxResponseBodySize)
 }
 
-// handleResponse reads http response body into byte slice
+// handleResponse reads http response body into byte slice, up to maxSize bytes if positive
 // if response contains an unexpected status code, error is returned
-func handleResponse(resp *http.Response, inErr error) (b []byte, response *http.Response, err error) {
+func handleResponse(resp *http.Response, inErr error, maxSize int64) (b []byte, response *http.Response, err error) {
 	if inErr != nil {
 		return nil, nil, inErr
 	}
@@ -44,13 +150,20 @@ func handleResponse(resp *http.Response, inErr error) (b []byte, response *http.
 	}()
 
 	response = resp
-	b, err = ioutil.ReadAll(resp.Body)
+	var body io.Reader = resp.Body
+	if maxSize > 0 {
+		body = io.LimitReader(resp.Body, maxSize+1)
+	}
+	b, err = ioutil.ReadAll(body)
 	if err != nil {
 		return nil, nil, err
 	}
+	if maxSize > 0 && int64(len(b)) > maxSize {
+		return nil, response, &ResponseTooLargeError{Limit: maxSize}
+	}
 
 	if !isOKResponseCode(resp.StatusCode) {
-		return nil, response, fmt.Errorf("Got unexpected response status code: %d. response: %s", resp.StatusCode, string(b))
+		return nil, response, &HTTPError{StatusCode: resp.StatusCode, Body: b}
 	}
 
 	if len(b) == 0 {
@@ -60,6 +173,16 @@ func handleResponse(resp *http.Response, inErr error) (b []byte, response *http.
 	return
 }
 
+// Error names the exceeded limit
+func (e *ResponseTooLargeError) Error() string {
+	return fmt.Sprintf("response body exceeds %d bytes, the HubHTTPClientOptions.MaxResponseBodySize limit", e.Limit)
+}
+
+// Error describes the status code and the response body
+func (e *HTTPError) Error() string {
+	return fmt.Sprintf("Got unexpected response status code: %d. response: %s", e.StatusCode, string(e.Body))
+}
+
 // isOKResponseCode identifies whether provided
 // response code matches the expected OK code
 func isOKResponseCode(code int) bool {
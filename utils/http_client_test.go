@@ -0,0 +1,80 @@
+package utils
+
+import (
+	"crypto/x509"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewHubHTTPClientDefaults(t *testing.T) {
+	client := NewHubHTTPClient().(HubHTTPClient)
+	if client.httpClient.Timeout != defaultRequestTimeout {
+		t.Errorf("expected timeout %s, got %s", defaultRequestTimeout, client.httpClient.Timeout)
+	}
+	transport, ok := client.httpClient.Transport.(*http.Transport)
+	if !ok {
+		t.Fatalf("expected *http.Transport, got %T", client.httpClient.Transport)
+	}
+	if transport.MaxIdleConnsPerHost != defaultMaxIdleConnsPerHost || transport.MaxConnsPerHost != defaultMaxConnsPerHost {
+		t.Errorf("expected connection pool limits, got %d idle and %d max per host", transport.MaxIdleConnsPerHost, transport.MaxConnsPerHost)
+	}
+	if !transport.ForceAttemptHTTP2 || transport.TLSHandshakeTimeout != defaultTLSHandshakeTimeout || transport.Proxy == nil {
+		t.Errorf("expected HTTP/2, TLS handshake timeout and environment proxy, got %+v", transport)
+	}
+	if client.maxResponseBodySize != defaultMaxResponseBodySize {
+		t.Errorf("expected max response body size %d, got %d", defaultMaxResponseBodySize, client.maxResponseBodySize)
+	}
+}
+
+func TestHubHTTPClientExec(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/slow":
+			time.Sleep(100 * time.Millisecond)
+		case "/large":
+			_, _ = w.Write([]byte(strings.Repeat("x", 100)))
+		case "/missing":
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte("not found"))
+		default:
+			_, _ = w.Write([]byte(r.Proto))
+		}
+	}))
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	defer server.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(server.Certificate())
+	client := NewHubHTTPClientWithOptions(&HubHTTPClientOptions{
+		Timeout:             50 * time.Millisecond,
+		RootCAs:             roots,
+		MaxResponseBodySize: 50,
+	})
+	get := func(path string) ([]byte, *http.Response, error) {
+		req, _ := http.NewRequest(http.MethodGet, server.URL+path, nil)
+		return client.Exec(req)
+	}
+
+	if b, _, err := get("/"); err != nil || string(b) != "HTTP/2.0" {
+		t.Errorf("expected HTTP/2.0 with the custom roots, got %s %v", b, err)
+	}
+	if _, _, err := get("/slow"); err == nil {
+		t.Error("expected timeout error")
+	}
+	if _, _, err := get("/large"); err == nil || !strings.Contains(err.Error(), "exceeds 50 bytes") {
+		t.Errorf("expected body size error, got %v", err)
+	} else if sizeErr, ok := err.(*ResponseTooLargeError); !ok || sizeErr.Limit != 50 {
+		t.Errorf("expected body size error, got %v", err)
+	}
+	_, response, err := get("/missing")
+	if httpErr, ok := err.(*HTTPError); !ok || httpErr.StatusCode != http.StatusNotFound || string(httpErr.Body) != "not found" {
+		t.Errorf("expected HTTPError 404, got %v", err)
+	}
+	if response == nil || response.StatusCode != http.StatusNotFound {
+		t.Errorf("expected 404 response, got %v", response)
+	}
+}
@@ -0,0 +1,101 @@
+package utils
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"net/http"
+)
+
+// RequestIDHeader is the header correlating a client request with the hub logs
+const RequestIDHeader = "x-ms-client-request-id"
+
+type (
+	// HTTPClientFunc is a function executing requests
+	HTTPClientFunc func(req *http.Request) ([]byte, *http.Response, error)
+
+	// Interceptor wraps an HTTPClient, for example to log, trace, add headers or collect metrics
+	Interceptor func(next HTTPClient) HTTPClient
+
+	// contextKey is the type of the context keys of the package
+	contextKey int
+)
+
+const (
+	operationKey contextKey = iota
+	requestIDKey
+)
+
+// Exec calls f(req)
+func (f HTTPClientFunc) Exec(req *http.Request) ([]byte, *http.Response, error) {
+	return f(req)
+}
+
+// Chain wraps the client with the interceptors, the first interceptor is the outermost
+func Chain(client HTTPClient, interceptors ...Interceptor) HTTPClient {
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		client = interceptors[i](client)
+	}
+	return client
+}
+
+// WithOperation returns a context carrying the operation name of a hub request
+func WithOperation(ctx context.Context, operation string) context.Context {
+	return context.WithValue(ctx, operationKey, operation)
+}
+
+// OperationFromContext returns the operation name of a hub request, or an empty string
+func OperationFromContext(ctx context.Context) string {
+	operation, _ := ctx.Value(operationKey).(string)
+	return operation
+}
+
+// WithRequestID returns a context carrying the request ID sent by RequestIDInterceptor
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey, requestID)
+}
+
+// RequestIDFromContext returns the request ID of the context, or an empty string
+func RequestIDFromContext(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDKey).(string)
+	return requestID
+}
+
+// RequestIDInterceptor sends the request ID of the context, or else a generated one, in the request ID header
+// A nil generate function generates random UUIDs
+func RequestIDInterceptor(generate func() string) Interceptor {
+	if generate == nil {
+		generate = NewUUID
+	}
+	return func(next HTTPClient) HTTPClient {
+		return HTTPClientFunc(func(req *http.Request) ([]byte, *http.Response, error) {
+			if req.Header.Get(RequestIDHeader) == "" {
+				requestID := RequestIDFromContext(req.Context())
+				if requestID == "" {
+					requestID = generate()
+				}
+				req.Header.Set(RequestIDHeader, requestID)
+			}
+			return next.Exec(req)
+		})
+	}
+}
+
+// UserAgentInterceptor sets the user agent of every request
+func UserAgentInterceptor(userAgent string) Interceptor {
+	return func(next HTTPClient) HTTPClient {
+		return HTTPClientFunc(func(req *http.Request) ([]byte, *http.Response, error) {
+			req.Header.Set("User-Agent", userAgent)
+			return next.Exec(req)
+		})
+	}
+}
+
+// NewUUID returns a random version 4 UUID
+func NewUUID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
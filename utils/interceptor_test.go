@@ -0,0 +1,71 @@
+package utils
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+func TestChain(t *testing.T) {
+	var (
+		calls  []string
+		record = func(name string) Interceptor {
+			return func(next HTTPClient) HTTPClient {
+				return HTTPClientFunc(func(req *http.Request) ([]byte, *http.Response, error) {
+					calls = append(calls, name)
+					return next.Exec(req)
+				})
+			}
+		}
+		client = Chain(&mockHTTPClient{}, record("outer"), record("inner"))
+	)
+
+	if _, _, err := client.Exec(newTestRequest(http.MethodGet, "/testhub")); err != nil {
+		t.Fatal(err)
+	}
+	if expected := []string{"outer", "inner"}; !reflect.DeepEqual(calls, expected) {
+		t.Errorf("expected calls %v, got %v", expected, calls)
+	}
+}
+
+func TestRequestIDInterceptor(t *testing.T) {
+	var (
+		headers []string
+		client  = Chain(HTTPClientFunc(func(req *http.Request) ([]byte, *http.Response, error) {
+			headers = append(headers, req.Header.Get(RequestIDHeader))
+			return nil, nil, nil
+		}), RequestIDInterceptor(nil))
+		withID    = newTestRequest(http.MethodGet, "/testhub")
+		withoutID = newTestRequest(http.MethodGet, "/testhub")
+	)
+	withID.Header, withoutID.Header = http.Header{}, http.Header{}
+
+	_, _, _ = client.Exec(withID.WithContext(WithRequestID(context.Background(), "request-1")))
+	_, _, _ = client.Exec(withoutID)
+
+	if headers[0] != "request-1" {
+		t.Errorf("expected context request ID, got %s", headers[0])
+	}
+	if !regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`).MatchString(headers[1]) {
+		t.Errorf("expected generated UUID, got %s", headers[1])
+	}
+}
+
+func TestUserAgentInterceptor(t *testing.T) {
+	var (
+		userAgent string
+		client    = Chain(HTTPClientFunc(func(req *http.Request) ([]byte, *http.Response, error) {
+			userAgent = req.UserAgent()
+			return nil, nil, nil
+		}), UserAgentInterceptor("test-agent/1.0"))
+		req = newTestRequest(http.MethodGet, "/testhub")
+	)
+	req.Header = http.Header{}
+
+	_, _, _ = client.Exec(req)
+	if userAgent != "test-agent/1.0" {
+		t.Errorf("expected user agent test-agent/1.0, got %s", userAgent)
+	}
+}
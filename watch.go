@@ -0,0 +1,142 @@
+package notificationhubs
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+	"time"
+)
+
+// Default polling settings for WatchNotification
+const (
+	defaultWatchInitialInterval = time.Second
+	defaultWatchMaxInterval     = 30 * time.Second
+	defaultWatchMultiplier      = 2
+	defaultWatchNotFoundTimeout = time.Minute
+)
+
+// WatchOptions configures how WatchNotification polls the notification details
+type WatchOptions struct {
+	// InitialInterval is the delay before the second poll, defaults to 1 second
+	InitialInterval time.Duration
+	// MaxInterval caps the delay between polls, defaults to 30 seconds
+	MaxInterval time.Duration
+	// Multiplier grows the delay while the details are unchanged, defaults to 2
+	Multiplier float64
+	// NotFoundTimeout is how long a missing notification is polled for, since the
+	// details are not available right after enqueueing. Defaults to 1 minute
+	NotFoundTimeout time.Duration
+	// EmitUnchanged sends every polled snapshot, not only the ones that changed
+	EmitUnchanged bool
+}
+
+// WatchNotification polls the notification details until the notification reaches a terminal state
+// Snapshots are sent on the first channel, which is closed after the terminal snapshot,
+// when the context is done or on error. The error, if any, is sent on the second channel.
+// Telemetry is only available for Standard tier Notification Hubs
+func (h *NotificationHub) WatchNotification(ctx context.Context, notificationID string, opts *WatchOptions) (<-chan *NotificationDetails, <-chan error) {
+	var (
+		snapshots = make(chan *NotificationDetails)
+		errs      = make(chan error, 1)
+	)
+	go func() {
+		defer close(errs)
+		defer close(snapshots)
+		if err := h.watchNotification(ctx, notificationID, opts.withDefaults(), snapshots); err != nil {
+			errs <- err
+		}
+	}()
+	return snapshots, errs
+}
+
+// WaitForCompletion blocks until the notification reaches a terminal state and returns its final details
+func (h *NotificationHub) WaitForCompletion(ctx context.Context, notificationID string, opts *WatchOptions) (*NotificationDetails, error) {
+	var (
+		last           *NotificationDetails
+		snapshots, err = h.WatchNotification(ctx, notificationID, opts)
+	)
+	for details := range snapshots {
+		last = details
+	}
+	if e := <-err; e != nil {
+		return last, e
+	}
+	if last == nil || !last.IsTerminal() {
+		return last, errors.New("notification did not reach a terminal state")
+	}
+	return last, nil
+}
+
+// watchNotification polls until a terminal state, the context is done or an error occurs
+func (h *NotificationHub) watchNotification(ctx context.Context, notificationID string, opts WatchOptions, snapshots chan<- *NotificationDetails) error {
+	var (
+		started  = time.Now()
+		interval = opts.InitialInterval
+		previous *NotificationDetails
+	)
+	for {
+		details, _, response, err := h.notificationDetails(ctx, notificationID)
+		switch {
+		case err == nil:
+			if opts.EmitUnchanged || !reflect.DeepEqual(details, previous) {
+				select {
+				case snapshots <- details:
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+				interval = opts.InitialInterval
+			} else {
+				interval = nextInterval(interval, opts)
+			}
+			if details.IsTerminal() {
+				return nil
+			}
+			previous = details
+		case response != nil && response.StatusCode == http.StatusNotFound && time.Since(started) < opts.NotFoundTimeout:
+			interval = nextInterval(interval, opts)
+		default:
+			return err
+		}
+
+		timer := time.NewTimer(interval)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+	}
+}
+
+// nextInterval grows the interval up to the maximum
+func nextInterval(interval time.Duration, opts WatchOptions) time.Duration {
+	if interval = time.Duration(float64(interval) * opts.Multiplier); interval > opts.MaxInterval {
+		return opts.MaxInterval
+	}
+	return interval
+}
+
+// withDefaults returns a copy of the options with the zero values replaced by the defaults
+func (o *WatchOptions) withDefaults() WatchOptions {
+	var opts WatchOptions
+	if o != nil {
+		opts = *o
+	}
+	if opts.InitialInterval <= 0 {
+		opts.InitialInterval = defaultWatchInitialInterval
+	}
+	if opts.MaxInterval <= 0 {
+		opts.MaxInterval = defaultWatchMaxInterval
+	}
+	if opts.MaxInterval < opts.InitialInterval {
+		opts.MaxInterval = opts.InitialInterval
+	}
+	if opts.Multiplier < 1 {
+		opts.Multiplier = defaultWatchMultiplier
+	}
+	if opts.NotFoundTimeout <= 0 {
+		opts.NotFoundTimeout = defaultWatchNotFoundTimeout
+	}
+	return opts
+}
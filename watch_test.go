@@ -0,0 +1,86 @@
+package notificationhubs_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/daresaydigital/azure-notificationhubs-go"
+)
+
+func detailsXML(state NotificationState) []byte {
+	return []byte(fmt.Sprintf("<NotificationDetails><NotificationId>%s</NotificationId><State>%s</State></NotificationDetails>", notificationID, state))
+}
+
+func Test_WatchNotification(t *testing.T) {
+	var (
+		nhub, mockClient = initTestItems()
+		requests         = 0
+		responses        = []NotificationState{"", Enqueued, Enqueued, Processing, Processing, Completed}
+		opts             = &WatchOptions{InitialInterval: time.Millisecond, MaxInterval: 5 * time.Millisecond}
+	)
+
+	mockClient.execFunc = func(req *http.Request) ([]byte, *http.Response, error) {
+		state := responses[requests]
+		requests++
+		if state == "" {
+			return nil, &http.Response{StatusCode: http.StatusNotFound}, errors.New("Got unexpected response status code: 404")
+		}
+		return detailsXML(state), &http.Response{StatusCode: http.StatusOK}, nil
+	}
+
+	snapshots, errs := nhub.WatchNotification(context.Background(), notificationID, opts)
+	var states []NotificationState
+	for details := range snapshots {
+		states = append(states, details.State)
+	}
+	if err := <-errs; err != nil {
+		t.Fatalf(errfmt, "error", nil, err)
+	}
+	if got, want := fmt.Sprint(states), fmt.Sprint([]NotificationState{Enqueued, Processing, Completed}); got != want {
+		t.Errorf(errfmt, "snapshots", want, got)
+	}
+	if requests != len(responses) {
+		t.Errorf(errfmt, "requests", len(responses), requests)
+	}
+
+	requests = 1
+	details, err := nhub.WaitForCompletion(context.Background(), notificationID, opts)
+	if err != nil {
+		t.Fatalf(errfmt, "error", nil, err)
+	}
+	if details.State != Completed {
+		t.Errorf(errfmt, "state", Completed, details.State)
+	}
+}
+
+func Test_WatchNotificationError(t *testing.T) {
+	var (
+		nhub, mockClient = initTestItems()
+		opts             = &WatchOptions{InitialInterval: time.Millisecond, NotFoundTimeout: 5 * time.Millisecond}
+	)
+
+	mockClient.execFunc = func(req *http.Request) ([]byte, *http.Response, error) {
+		return nil, &http.Response{StatusCode: http.StatusNotFound}, errors.New("Got unexpected response status code: 404")
+	}
+	if _, err := nhub.WaitForCompletion(context.Background(), notificationID, opts); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf(errfmt, "error", "404", err)
+	}
+
+	mockClient.execFunc = func(req *http.Request) ([]byte, *http.Response, error) {
+		return detailsXML(Processing), &http.Response{StatusCode: http.StatusOK}, nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	details, err := nhub.WaitForCompletion(ctx, notificationID, opts)
+	if err != context.DeadlineExceeded {
+		t.Errorf(errfmt, "error", context.DeadlineExceeded, err)
+	}
+	if details == nil || details.State != Processing {
+		t.Errorf(errfmt, "last snapshot", Processing, details)
+	}
+}